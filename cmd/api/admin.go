@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"piscine/internal/validator"
+	"strconv"
+)
+
+const reindexBatchSize = 1000
+
+// setMaintenanceMode switches maintenance mode on or off at runtime, so writes
+// can be paused around a deploy without restarting the server.
+func (app *application) setMaintenanceMode(enabled bool, actor string) {
+	if app.maintenance.Swap(enabled) == enabled {
+		return
+	}
+
+	message := "maintenance mode disabled"
+	if enabled {
+		message = "maintenance mode enabled"
+	}
+	app.logger.PrintInfo(message, map[string]string{
+		"actor": actor,
+	})
+}
+
+func (app *application) maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.CheckCode(input.Enabled != nil, "enabled", "required", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	app.setMaintenanceMode(*input.Enabled, app.contextGetUser(r).Email)
+
+	env := map[string]bool{"enabled": app.maintenance.Load()}
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("maintenance", env, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// setReadOnlyMode switches read-only mode on or off at runtime.
+func (app *application) setReadOnlyMode(enabled bool, actor string) {
+	if app.readOnly.Swap(enabled) == enabled {
+		return
+	}
+
+	message := "read-only mode disabled"
+	if enabled {
+		message = "read-only mode enabled"
+	}
+	app.logger.PrintInfo(message, map[string]string{
+		"actor": actor,
+	})
+}
+
+func (app *application) readOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.CheckCode(input.Enabled != nil, "enabled", "required", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	app.setReadOnlyMode(*input.Enabled, app.contextGetUser(r).Email)
+
+	env := map[string]bool{"enabled": app.readOnly.Load()}
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("read_only", env, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reindexHandler backfills the stored full-text search vectors in the
+// background, in batches so no single statement holds locks for long. Only one
+// reindex runs at a time.
+func (app *application) reindexHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.reindexing.CompareAndSwap(false, true) {
+		app.reindexInProgressResponse(w, r)
+		return
+	}
+
+	app.background(func() {
+		defer app.reindexing.Store(false)
+
+		var total int64
+		for {
+			updated, err := app.models.Footballers.ReindexSearchVectors(reindexBatchSize)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"reindexed": strconv.FormatInt(total, 10)})
+				return
+			}
+			if updated == 0 {
+				break
+			}
+			total += updated
+		}
+
+		app.logger.PrintInfo("search vectors reindexed", map[string]string{
+			"count": strconv.FormatInt(total, 10),
+		})
+	})
+
+	env := envelope{"message": "reindex started"}
+	err := app.writeJSON(w, r, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"piscine/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// clubStatsHandler returns the player count and goal and title totals for a
+// club, matched by name without regard to case.
+func (app *application) clubStatsHandler(w http.ResponseWriter, r *http.Request) {
+	club := httprouter.ParamsFromContext(r.Context()).ByName("name")
+
+	stats, err := app.footballers(r).ClubStats(club)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("stats", stats, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// incompressibleTypes are content type prefixes that compression is skipped for:
+// formats that are already compressed, and event streams, which stay open for
+// hours and would each hold a compressor's buffers for that long.
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-brotli",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"text/event-stream",
+}
+
+// compressible reports whether a response of the given content type is worth
+// compressing.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodings are the content codings responses can be compressed with, in
+// order of preference. Brotli packs JSON tighter than gzip at a similar CPU
+// cost, so it wins whenever the client accepts both.
+var encodings = []string{"br", "gzip"}
+
+// negotiateEncoding returns the preferred encoding the request's
+// Accept-Encoding allows, either by name or through "*", or "" if it allows
+// none. q=0 is honoured as a refusal.
+func negotiateEncoding(r *http.Request) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+
+		ok := true
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				ok = err == nil && q > 0
+			}
+		}
+		accepted[coding] = ok
+	}
+
+	for _, encoding := range encodings {
+		if ok, named := accepted[encoding]; named {
+			if ok {
+				return encoding
+			}
+			continue
+		}
+		if accepted["*"] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressor is implemented by both *gzip.Writer and *brotli.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressors pools a compressor per encoding. Brotli uses a mid level: the
+// higher ones cost several times the CPU for a few percent smaller bodies.
+var compressors = map[string]*sync.Pool{
+	"br": {
+		New: func() interface{} {
+			return brotli.NewWriterLevel(io.Discard, 5)
+		},
+	},
+	"gzip": {
+		New: func() interface{} {
+			return gzip.NewWriter(io.Discard)
+		},
+	},
+}
+
+// compressResponseWriter holds back the start of a response until it reaches
+// minBytes, then decides from its content type whether to compress it. Smaller
+// responses are sent as they are, since compressing them costs more CPU than
+// it saves in bandwidth.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding   string
+	minBytes   int
+	statusCode int
+	buf        []byte
+	decided    bool
+	enc        compressor
+}
+
+func (gw *compressResponseWriter) WriteHeader(statusCode int) {
+	if gw.decided || gw.statusCode != 0 {
+		return
+	}
+	gw.statusCode = statusCode
+
+	// Responses without a body have nothing to compress.
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		gw.decide(false)
+	}
+}
+
+func (gw *compressResponseWriter) Write(b []byte) (int, error) {
+	if !gw.decided {
+		gw.buf = append(gw.buf, b...)
+		if len(gw.buf) < gw.minBytes {
+			return len(b), nil
+		}
+		return len(b), gw.decide(true)
+	}
+
+	if gw.enc != nil {
+		return gw.enc.Write(b)
+	}
+	return gw.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far. A handler that flushes is
+// streaming, so the response is compressed from here on even if it is still
+// below minBytes.
+func (gw *compressResponseWriter) Flush() {
+	if !gw.decided {
+		gw.decide(true)
+	}
+	if gw.enc != nil {
+		gw.enc.Flush()
+	}
+	if flusher, ok := gw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (gw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// decide writes the header, compressed if compress is set and the content
+// type allows it, followed by the buffered start of the body.
+func (gw *compressResponseWriter) decide(compress bool) error {
+	gw.decided = true
+	if gw.statusCode == 0 {
+		gw.statusCode = http.StatusOK
+	}
+
+	header := gw.Header()
+	if compress && header.Get("Content-Encoding") == "" {
+		// net/http would sniff the compressed bytes, so sniff the
+		// plain ones instead.
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(gw.buf))
+		}
+		if compressible(header.Get("Content-Type")) {
+			header.Set("Content-Encoding", gw.encoding)
+			header.Del("Content-Length")
+			gw.enc = compressors[gw.encoding].Get().(compressor)
+			gw.enc.Reset(gw.ResponseWriter)
+		}
+	}
+
+	gw.ResponseWriter.WriteHeader(gw.statusCode)
+
+	buf := gw.buf
+	gw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if gw.enc != nil {
+		_, err := gw.enc.Write(buf)
+		return err
+	}
+	_, err := gw.ResponseWriter.Write(buf)
+	return err
+}
+
+// close sends a response that never reached minBytes as it is, and finishes
+// the compressed stream of one that did.
+func (gw *compressResponseWriter) close() {
+	if !gw.decided {
+		gw.decide(false)
+	}
+	if gw.enc != nil {
+		gw.enc.Close()
+		gw.enc.Reset(io.Discard)
+		compressors[gw.encoding].Put(gw.enc)
+		gw.enc = nil
+	}
+}
+
+// compress brotli or gzip encodes responses of at least -gzip-min-bytes for
+// clients that accept either, preferring brotli.
+func (app *application) compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r)
+		if app.config.gzip.minBytes < 0 || encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &compressResponseWriter{ResponseWriter: w, encoding: encoding, minBytes: app.config.gzip.minBytes}
+		defer gw.close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
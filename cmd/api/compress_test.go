@@ -0,0 +1,110 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{"None", "", ""},
+		{"Gzip only", "gzip", "gzip"},
+		{"Brotli preferred", "gzip, deflate, br", "br"},
+		{"Brotli refused", "gzip, br;q=0", "gzip"},
+		{"Wildcard", "*", "br"},
+		{"Wildcard without brotli", "*, br;q=0", "gzip"},
+		{"Everything refused", "br;q=0, gzip;q=0, *", ""},
+		{"Identity", "identity", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/footballer", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			if got := negotiateEncoding(r); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// footballersBody stands in for a large list payload.
+var footballersBody = strings.Repeat(messiJSON, 200)
+
+var listHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, footballersBody)
+})
+
+func TestCompress(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		decode         func(io.Reader) (io.Reader, error)
+	}{
+		{"br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.gzip.minBytes = 1024
+
+			header := http.Header{}
+			header.Set("Accept-Encoding", tt.acceptEncoding)
+			rr := do(t, app.compress(listHandler), http.MethodGet, "/v1/footballer", "", header)
+
+			if got := rr.Header().Get("Content-Encoding"); got != tt.acceptEncoding {
+				t.Fatalf("got Content-Encoding %q; want %q", got, tt.acceptEncoding)
+			}
+
+			body, err := tt.decode(rr.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plain, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plain) != footballersBody {
+				t.Errorf("decoded body does not match the response")
+			}
+		})
+	}
+}
+
+// BenchmarkCompress measures the CPU cost of each encoding on a list payload.
+// Compare the ns/op with the identity run, and the B/body metric with the
+// plain body size.
+func BenchmarkCompress(b *testing.B) {
+	for _, encoding := range []string{"identity", "gzip", "br"} {
+		b.Run(encoding, func(b *testing.B) {
+			app := &application{}
+			app.config.gzip.minBytes = 1024
+
+			handler := app.compress(listHandler)
+			r := httptest.NewRequest(http.MethodGet, "/v1/footballer", nil)
+			r.Header.Set("Accept-Encoding", encoding)
+
+			b.ReportAllocs()
+			var size int
+			for i := 0; i < b.N; i++ {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, r)
+				size = rr.Body.Len()
+			}
+			b.ReportMetric(float64(size), "B/body")
+		})
+	}
+}
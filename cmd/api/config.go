@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"net"
+	"net/url"
+	"os"
+	"piscine/internal/data"
+	"piscine/internal/validator"
+	"strings"
+	"time"
+)
+
+// loadConfig fills in every flag that wasn't given on the command line, first
+// from the JSON file named by -config and then from environment variables.
+// Precedence is command-line flag > environment variable > config file >
+// default. A flag's environment variable is its name uppercased with dashes
+// replaced by underscores, e.g. -db-dsn becomes DB_DSN.
+func loadConfig(fs *flag.FlagSet, configFile string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if configFile != "" {
+		content, err := os.ReadFile(configFile)
+		if err != nil {
+			return fmt.Errorf("reading config file: %w", err)
+		}
+
+		var values map[string]interface{}
+		err = json.Unmarshal(content, &values)
+		if err != nil {
+			return fmt.Errorf("parsing config file: %w", err)
+		}
+
+		for name, value := range values {
+			if fs.Lookup(name) == nil {
+				return fmt.Errorf("config file: unknown setting %q", name)
+			}
+			if explicit[name] {
+				continue
+			}
+			err = fs.Set(name, fmt.Sprint(value))
+			if err != nil {
+				return fmt.Errorf("config file: invalid value for %q: %w", name, err)
+			}
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+
+		key := strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(key); ok {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("environment variable %s: %w", key, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// validateConfig checks the settings that the application can't run without,
+// so a misconfiguration fails at startup rather than on the first request.
+func validateConfig(cfg config) error {
+	var problems []string
+
+	if cfg.port < 1 || cfg.port > 65535 {
+		problems = append(problems, "-port must be between 1 and 65535")
+	}
+	if cfg.env != "development" && cfg.env != "staging" && cfg.env != "production" {
+		problems = append(problems, "-env must be development, staging or production")
+	}
+	if cfg.db.dsn == "" {
+		problems = append(problems, "-db-dsn (DB_DSN) must be provided")
+	}
+	if cfg.pagination.defaultPageSize < 1 || cfg.pagination.defaultPageSize > cfg.pagination.maxPageSize {
+		problems = append(problems, "-pagination-default must be between 1 and -pagination-max")
+	}
+	if cfg.pagination.maxOffset < 0 {
+		problems = append(problems, "-pagination-max-offset must not be negative")
+	}
+	if !validator.In(cfg.defaultSort, data.FootballerSortSafelist...) {
+		problems = append(problems, "-default-sort must be one of "+strings.Join(data.FootballerSortSafelist, ", "))
+	}
+	if cfg.limiter.enabled && (cfg.limiter.rps <= 0 || cfg.limiter.userRps <= 0) {
+		problems = append(problems, "-limiter-rps and -limiter-user-rps must be positive when the rate limiter is enabled")
+	}
+	if cfg.listCache.enabled && (cfg.listCache.size < 1 || cfg.listCache.ttl <= 0) {
+		problems = append(problems, "-list-cache-size and -list-cache-ttl must be positive when the list cache is enabled")
+	}
+	if cfg.cors.allowCredentials {
+		for _, origin := range cfg.cors.trustedOrigins {
+			if origin == "*" {
+				problems = append(problems, "-cors-trusted-origins must not contain * when -cors-allow-credentials is set")
+				break
+			}
+		}
+	}
+	if cfg.cors.maxAge < 0 {
+		problems = append(problems, "-cors-max-age must not be negative")
+	}
+	// footballers_length_check in the database only allows 1 to 6 positions,
+	// so anything wider would pass validation and then fail on insert.
+	if cfg.validation.minPositions < 1 {
+		problems = append(problems, "-min-positions must be at least 1")
+	}
+	if cfg.validation.maxPositions > 6 {
+		problems = append(problems, "-max-positions must be at most 6")
+	}
+	if cfg.validation.maxPositions < cfg.validation.minPositions {
+		problems = append(problems, "-max-positions must not be less than -min-positions")
+	}
+	if cfg.validation.maxTitlesPerClub < 1 {
+		problems = append(problems, "-max-titles-per-club must be at least 1")
+	}
+	if !validator.In(cfg.timeFormat, data.TimeFormats...) {
+		problems = append(problems, "-time-format must be rfc3339 or unix")
+	}
+	if cfg.bcryptCost < bcrypt.MinCost || cfg.bcryptCost > bcrypt.MaxCost {
+		problems = append(problems, fmt.Sprintf("-bcrypt-cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if cfg.db.slowQuery < 0 {
+		problems = append(problems, "-db-slow-query-threshold must not be negative")
+	}
+	if cfg.db.connectWait < 0 {
+		problems = append(problems, "-db-connect-max-wait must not be negative")
+	}
+	if cfg.server.requestTimeout < 0 {
+		problems = append(problems, "-request-timeout must not be negative")
+	}
+	if cfg.maintenance.retryAfter < time.Second {
+		problems = append(problems, "-maintenance-retry-after must be at least 1s")
+	}
+	if cfg.stream.maxSubscribers < 1 {
+		problems = append(problems, "-stream-max-subscribers must be at least 1")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// parseBaseURL parses the -base-url value, which must be an absolute http or
+// https URL without a query or fragment.
+func parseBaseURL(val string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(val))
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("must be an absolute http or https URL")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("must not contain a query or fragment")
+	}
+	return u, nil
+}
+
+// parseCIDRs parses a comma-separated list of CIDR blocks.
+func parseCIDRs(val string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(val, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -22,3 +22,23 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+const featuresContextKey = contextKey("features")
+
+func (app *application) contextSetFeatures(r *http.Request, features map[string]bool) *http.Request {
+	ctx := context.WithValue(r.Context(), featuresContextKey, features)
+	return r.WithContext(ctx)
+}
+
+// contextHasFeature reports whether the client turned on the named
+// experimental feature with the X-Feature-Flags header. Features are off by
+// default.
+func (app *application) contextHasFeature(r *http.Request, name string) bool {
+	features, _ := r.Context().Value(featuresContextKey).(map[string]bool)
+	return features[name]
+}
+
+// authenticatedContextKey holds a flag the per-user rate limiter sets once a
+// request's token has checked out, telling the per-IP limiter in front of
+// authenticate not to charge the request to the client IP.
+const authenticatedContextKey = contextKey("authenticated")
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"piscine/internal/data"
+)
+
+//go:embed "fixtures"
+var fixturesFS embed.FS
+
+// resetHandler wipes the footballers table and reloads the embedded fixture,
+// so end-to-end tests start from a known state. The route is only registered
+// when -env is development; the env check here is a second line of defence.
+func (app *application) resetHandler(w http.ResponseWriter, r *http.Request) {
+	if app.config.env != "development" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	fixture, err := fixturesFS.ReadFile("fixtures/footballers.json")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var footballers []*data.Footballer
+	err = json.Unmarshal(fixture, &footballers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.footballers(r).Reset(footballers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, footballer := range footballers {
+		app.indexFootballer(footballer)
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("result", map[string]int{"count": len(footballers)}, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
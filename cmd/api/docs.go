@@ -0,0 +1,33 @@
+package main
+
+import (
+	"embed"
+	"net/http"
+)
+
+//go:embed "docs"
+var docsFS embed.FS
+
+func (app *application) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	spec, err := docsFS.ReadFile("docs/openapi.json")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(spec)
+}
+
+func (app *application) swaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := docsFS.ReadFile("docs/swagger.html")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page)
+}
@@ -1,37 +1,101 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"piscine/internal/data"
+	"piscine/internal/i18n"
+	"piscine/internal/validator"
+	"runtime/debug"
+	"strings"
 )
 
 func (app *application) logError(r *http.Request, err error) {
 	app.logger.PrintError(err, map[string]string{
 		"request_method": r.Method,
 		"request_url":    r.URL.String(),
+		"client_ip":      app.clientIP(r),
 	})
 }
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
 	env := envelope{"error": message}
 
-	err := app.writeJSON(w, status, env, nil)
+	err := app.writeJSON(w, r, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(500)
 	}
 }
 
+// serverErrorResponse always logs the full error. In development the response
+// also carries the error text and the top of the stack trace.
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		app.requestTimeoutResponse(w, r, err)
+		return
+	}
+	if data.IsTimeout(err) {
+		app.databaseTimeoutResponse(w, r, err)
+		return
+	}
+
 	app.logError(r, err)
 	message := "the server encountered a problem and could not process your request"
+
+	if app.config.env == "development" {
+		stack := strings.Split(string(debug.Stack()), "\n")
+		if len(stack) > 20 {
+			stack = stack[:20]
+		}
+		app.errorResponse(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message": message,
+			"detail":  err.Error(),
+			"stack":   stack,
+		})
+		return
+	}
+
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+// databaseTimeoutResponse reports a query that hit its deadline as 503, so
+// clients and monitors can tell an overloaded database apart from a bug.
+func (app *application) databaseTimeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.PrintError(err, map[string]string{
+		"kind":           "database_timeout",
+		"request_method": r.Method,
+		"request_url":    r.URL.String(),
+		"client_ip":      app.clientIP(r),
+	})
+
+	message := "database timeout, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+// requestTimeoutResponse reports a request that ran past the -request-timeout
+// deadline set by the timeout middleware.
+func (app *application) requestTimeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.PrintError(err, map[string]string{
+		"kind":           "request_timeout",
+		"request_method": r.Method,
+		"request_url":    r.URL.String(),
+		"client_ip":      app.clientIP(r),
+	})
+
+	message := "the request took too long to process, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource could not be found"
 	app.errorResponse(w, r, http.StatusNotFound, message)
 }
 
+// methodNotAllowedResponse is the router's MethodNotAllowed handler. httprouter
+// sets the Allow header to the methods registered for the path before calling
+// it.
 func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
 	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
@@ -41,8 +105,27 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
-func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+// failedValidationResponse translates the validation messages into the
+// language requested by the Accept-Language header (English by default). The
+// messages stay under "error"; "codes" carries the stable error code for each
+// key.
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator) {
+	lang := i18n.PreferredLanguage(r.Header.Get("Accept-Language"))
+
+	translated := make(map[string]string, len(v.Errors))
+	for key, message := range v.Errors {
+		translated[key] = i18n.Translate(lang, message)
+	}
+
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+
+	env := envelope{"error": translated, "codes": v.Codes}
+	err := app.writeJSON(w, r, http.StatusUnprocessableEntity, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(500)
+	}
 }
 
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
@@ -50,11 +133,41 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (app *application) duplicateFootballerResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a footballer with this name and club already exists, add ?force=true to create it anyway"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) uniqueFootballerResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a footballer with this name, club and started_play_year already exists"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	app.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
 
+func (app *application) maintenanceModeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the server is in maintenance mode, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (app *application) readOnlyModeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the server is in read-only mode, changes are disabled"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (app *application) reindexInProgressResponse(w http.ResponseWriter, r *http.Request) {
+	message := "a search reindex is already running"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (app *application) tooManySubscribersResponse(w http.ResponseWriter, r *http.Request) {
+	message := "too many clients are connected to the stream, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
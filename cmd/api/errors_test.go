@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerErrorResponseTimeouts(t *testing.T) {
+	// A query run under an expired context fails with the context's error.
+	expired, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	<-expired.Done()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		wantStatus int
+	}{
+		{"Query deadline", context.Background(), fmt.Errorf("query: %w", expired.Err()), http.StatusServiceUnavailable},
+		{"Statement canceled by PostgreSQL", context.Background(), &pq.Error{Code: "57014"}, http.StatusServiceUnavailable},
+		{"Request deadline", expired, expired.Err(), http.StatusServiceUnavailable},
+		{"Other error", context.Background(), errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/footballer", nil).WithContext(tt.ctx)
+			rr := httptest.NewRecorder()
+			app.serverErrorResponse(rr, r, tt.err)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"mime"
 	"net/http"
+	"net/url"
 	"piscine/internal/data"
+	"piscine/internal/search"
 	"piscine/internal/validator"
+	"reflect"
+	"strings"
+	"time"
 )
 
 func (app *application) createFootballerHandler(w http.ResponseWriter, r *http.Request) {
@@ -18,6 +27,9 @@ func (app *application) createFootballerHandler(w http.ResponseWriter, r *http.R
 		PlayedClubs     int      `json:"played_clubs"`
 		Position        []string `json:"position"`
 		Goals           int      `json:"goals"`
+		PreferredFoot   string   `json:"preferred_foot"`
+		HeightCm        *int32   `json:"height_cm"`
+		WeightKg        *int32   `json:"weight_kg"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -34,37 +46,129 @@ func (app *application) createFootballerHandler(w http.ResponseWriter, r *http.R
 		PlayedClubs:     input.PlayedClubs,
 		Position:        input.Position,
 		Goals:           input.Goals,
+		PreferredFoot:   input.PreferredFoot,
+		HeightCm:        input.HeightCm,
+		WeightKg:        input.WeightKg,
 	}
+	data.NormalizeFootballer(footballer)
+
 	v := validator.New()
 
-	if data.ValidateFootballer(v, footballer); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	dryRun := app.readDryRun(r, v)
+	onConflict := app.readString(r.URL.Query(), "on_conflict", "error")
+	v.CheckCode(validator.In(onConflict, "error", "ignore"), "on_conflict", "invalid", "must be error or ignore")
+	if app.validateFootballer(r, v, footballer); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if onConflict == "ignore" && !dryRun {
+		app.createFootballerIfNotExists(w, r, footballer)
+		return
+	}
+
+	if r.URL.Query().Get("force") != "true" {
+		exists, err := app.footballers(r).ExistsByNameAndClub(footballer.Name, footballer.Club)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if exists {
+			app.duplicateFootballerResponse(w, r)
+			return
+		}
+	}
+
+	if dryRun {
+		footballer.Roles = data.PositionRoles(footballer.Position)
+		app.dryRunResponse(w, r, footballer)
 		return
 	}
 
-	err = app.models.Footballers.Insert(footballer)
+	err = app.footballers(r).Insert(footballer)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateFootballer):
+			app.uniqueFootballerResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	app.indexFootballer(footballer)
+	app.events.Publish("created", footballer)
+
+	if app.webhooks != nil {
+		app.webhooks.Enqueue("footballer.created", footballer)
+	}
+
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/v1/footballer/%d", footballer.ID))
+	headers.Set("Location", app.absoluteURL(r, fmt.Sprintf("/v1/footballer/%d", footballer.ID)))
+
+	err = app.writeJSON(w, r, http.StatusCreated, app.dataEnvelope("footballer", footballer, nil), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"footballer": footballer}, headers)
+}
+
+// createFootballerIfNotExists handles ?on_conflict=ignore: it creates the
+// footballer unless one with the same name and club exists, and answers 200
+// with that one instead of a conflict, so seeding scripts can be rerun.
+func (app *application) createFootballerIfNotExists(w http.ResponseWriter, r *http.Request, footballer *data.Footballer) {
+	created, err := app.footballers(r).Upsert(footballer)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+
+		app.indexFootballer(footballer)
+		app.events.Publish("created", footballer)
+
+		if app.webhooks != nil {
+			app.webhooks.Enqueue("footballer.created", footballer)
+		}
 	}
 
+	headers := make(http.Header)
+	headers.Set("Location", app.absoluteURL(r, fmt.Sprintf("/v1/footballer/%d", footballer.ID)))
+
+	err = app.writeJSON(w, r, status, app.dataEnvelope("footballer", footballer, nil), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *application) showFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	if strings.HasSuffix(params.ByName("id"), ".jsonld") {
+		app.showFootballerJSONLDHandler(w, r)
+		return
+	}
+
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
-	footballer, err := app.models.Footballers.Get(id)
+
+	v := validator.New()
+
+	fields := app.readCSV(r.URL.Query(), "fields", []string{})
+	data.ValidateFields(v, fields)
+	imperial := app.readImperial(r.URL.Query(), v)
+	timeFormat := app.readTimeFormat(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -75,20 +179,33 @@ func (app *application) showFootballerHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"footballer": footballer}, nil)
+	if imperial {
+		footballer.ToImperial()
+	}
+	footballer.TimeFormat = timeFormat
+
+	var output interface{} = footballer
+	if len(fields) > 0 {
+		output = footballer.Select(fields)
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", footballerETag(footballer.Version))
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", output, nil), headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-func (app *application) updateFootballerHandler(w http.ResponseWriter, r *http.Request) {
+func (app *application) existsFootballerHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	footballer, err := app.models.Footballers.Get(id)
+	version, err := app.footballers(r).Version(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -99,78 +216,195 @@ func (app *application) updateFootballerHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	var input struct {
-		Name            *string   `json:"name"`
-		Titles          *int      `json:"titles"`
-		StartedPlayYear *int32    `json:"started_play_year"`
-		Year            *int32    `json:"year"`
-		Club            *string   `json:"club"`
-		PlayedClubs     *int      `json:"played_clubs"`
-		Position        []string `json:"position"`
-		Goals           *int      `json:"goals"`
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", footballerETag(version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// footballerETag is the entity tag of a footballer: its version, which every
+// update increments.
+func footballerETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+func (app *application) updateFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
 	}
 
-	err = app.readJSON(w, r, &input)
+	footballer, err := app.footballers(r).Get(id)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
-	if input.Name != nil {
-		footballer.Name = *input.Name
+
+	if isMergePatch(r) {
+		err = app.readFootballerMergePatch(w, r, footballer)
+	} else {
+		err = app.readFootballerPatch(w, r, footballer)
 	}
-	if input.Titles != nil {
-		footballer.Titles = *input.Titles
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
-	if input.StartedPlayYear != nil {
-		footballer.StartedPlayYear = *input.StartedPlayYear
+
+	data.NormalizeFootballer(footballer)
+
+	v := validator.New()
+	dryRun := app.readDryRun(r, v)
+	if app.validateFootballer(r, v, footballer); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
 	}
-	if input.Year != nil {
-		footballer.Year = *input.Year
+
+	if dryRun {
+		footballer.Roles = data.PositionRoles(footballer.Position)
+		footballer.Version++
+		app.dryRunResponse(w, r, footballer)
+		return
 	}
-	if input.Club != nil {
-		footballer.Club = *input.Club
+
+	err = app.footballers(r).Update(footballer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateFootballer):
+			app.uniqueFootballerResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
 	}
-	if input.PlayedClubs != nil {
-		footballer.PlayedClubs = *input.PlayedClubs
+
+	app.indexFootballer(footballer)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
 	}
-	if input.Position != nil {
-		footballer.Position = input.Position
+}
+
+func (app *application) updateRecentGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
 	}
-	if input.Goals != nil {
-		footballer.Goals = *input.Goals
+
+	var input struct {
+		RecentGoals *int `json:"recent_goals"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	v := validator.New()
-	if data.ValidateFootballer(v, footballer); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	v.CheckCode(input.RecentGoals != nil, "recent_goals", "required", "must be provided")
+	v.CheckCode(input.RecentGoals == nil || *input.RecentGoals >= 0, "recent_goals", "too_small", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	err = app.models.Footballers.Update(footballer)
+	err = app.footballers(r).UpdateRecentGoals(id, *input.RecentGoals)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"footballer": footballer}, nil)
+	app.indexFootballer(footballer)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-func (app *application) deleteFootballerHandler(w http.ResponseWriter, r *http.Request) {
+// footballerSchemaHandler publishes the JSON Schema of the footballer create
+// body, so clients can validate input before sending it.
+func (app *application) footballerSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/schema+json")
+
+	err := app.writeJSON(w, r, http.StatusOK, envelope(data.FootballerSchema()), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// changeClubHandler records a transfer. Moving to a different club also counts
+// it in played_clubs; setting the current club again only bumps the version.
+func (app *application) changeClubHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.Footballers.Delete(id)
+	var input struct {
+		Club *string `json:"club"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var club string
+	if input.Club != nil {
+		club = strings.Join(strings.Fields(*input.Club), " ")
+	}
+
+	v := validator.New()
+	v.CheckCode(club != "", "club", "required", "must be provided")
+	v.CheckCode(len(club) <= 500, "club", "too_long", "must not be more than 500 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.footballers(r).ChangeClub(id, club)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateFootballer):
+			app.uniqueFootballerResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -181,48 +415,999 @@ func (app *application) deleteFootballerHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "footballer successfully deleted"}, nil)
+	app.indexFootballer(footballer)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-func (app *application) listFootballerHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Name            string
-		Club            string
-		Position        []string
-		data.Filters
+func (app *application) incrementGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	var input []data.GoalsIncrement
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	v := validator.New()
+	if data.ValidateGoalsIncrements(v, input); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
 
-	qs := r.URL.Query()
+	totals, err := app.footballers(r).IncrementGoals(input)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddErrorCode("goals", "not_found", err.Error())
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrNegativeGoals):
+			v.AddErrorCode("goals", "too_small", err.Error())
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
 
-	input.Name = app.readString(qs,"names","")
-	input.Club = app.readString(qs,"club","")
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballers", totals, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
 
-	input.Position = app.readCSV(qs,"positions",[]string{})
+func (app *application) verifyFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
 
-	input.Filters.Page = app.readInt(qs, "page", 1, v)
-	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	var input struct {
+		Verified *bool `json:"verified"`
+	}
 
-	input.Filters.Sort = app.readString(qs, "sort", "id")
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
-	input.Filters.SortSafelist = []string{"id","names","titles","startedplayyear","year","goals","-id","-names","-titles","-startedplayyear","-year","-goals"}
+	v := validator.New()
+	if v.CheckCode(input.Verified != nil, "verified", "required", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
 
-	if data.ValidateFilters(v, input.Filters); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	err = app.footballers(r).SetVerified(id, *input.Verified)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	footballers,metadata, err := app.models.Footballers.GetAll(input.Name,input.Club,input.Position,input.Filters)
+	footballer, err := app.footballers(r).Get(id)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, envelope{"footballers": footballers, "metadata":metadata}, nil)
+
+	app.indexFootballer(footballer)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
-}
\ No newline at end of file
+}
+
+func (app *application) updateInjuryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Injured          *bool      `json:"injured"`
+		InjuryReturnDate *time.Time `json:"injury_return_date"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Injured != nil {
+		footballer.Injured = *input.Injured
+	}
+	if input.InjuryReturnDate != nil {
+		footballer.InjuryReturnDate = input.InjuryReturnDate
+	}
+	if !footballer.Injured {
+		footballer.InjuryReturnDate = nil
+	}
+
+	v := validator.New()
+	if data.ValidateInjury(v, footballer); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.footballers(r).UpdateInjury(id, footballer.Injured, footballer.InjuryReturnDate)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	footballer, err = app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.indexFootballer(footballer)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.footballers(r).Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.unindexFootballer(id)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "footballer successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// mergeFootballersHandler folds a duplicate footballer into the one to keep
+// and returns the kept footballer.
+func (app *application) mergeFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Keep   int64 `json:"keep"`
+		Remove int64 `json:"remove"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.CheckCode(input.Keep > 0, "keep", "too_small", "must be a positive integer")
+	v.CheckCode(input.Remove > 0, "remove", "too_small", "must be a positive integer")
+	v.CheckCode(input.Remove != input.Keep, "remove", "invalid", "must be different from keep")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.footballers(r).Merge(input.Keep, input.Remove)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.unindexFootballer(input.Remove)
+
+	footballer, err := app.footballers(r).Get(input.Keep)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readFootballerQuery reads the filters shared by the list and count endpoints.
+func (app *application) readFootballerQuery(qs url.Values, v *validator.Validator) data.FootballerQuery {
+	var q data.FootballerQuery
+
+	q.Name = app.readString(qs, "names", "")
+	q.Search = app.readString(qs, "q", "")
+	q.Club = app.readString(qs, "club", "")
+
+	q.Position = app.readCSV(qs, "positions", []string{})
+	data.NormalizePositions(q.Position)
+	q.PositionMatch = app.readString(qs, "position_match", data.PositionMatchAll)
+	v.CheckCode(validator.In(q.PositionMatch, data.PositionMatchAll, data.PositionMatchAny), "position_match", "invalid", "must be all or any")
+	q.Role = app.readString(qs, "role", "")
+	v.CheckCode(q.Role == "" || validator.In(q.Role, data.Roles...), "role", "invalid", "invalid role value")
+	q.Verified = app.readBool(qs, "verified", v)
+	q.Injured = app.readBool(qs, "injured", v)
+	q.Foot = strings.ToLower(app.readString(qs, "foot", ""))
+	v.CheckCode(q.Foot == "" || validator.In(q.Foot, data.PreferredFeet...), "foot", "invalid", "must be left, right or both")
+	q.MinHeight = int32(app.readInt(qs, "min_height", 0, v))
+	q.MaxHeight = int32(app.readInt(qs, "max_height", 0, v))
+	v.CheckCode(q.MinHeight >= 0, "min_height", "too_small", "must not be negative")
+	v.CheckCode(q.MaxHeight >= 0, "max_height", "too_small", "must not be negative")
+	v.CheckCode(q.MaxHeight == 0 || q.MinHeight <= q.MaxHeight, "max_height", "too_small", "must not be less than min_height")
+	q.UpdatedSince = app.readTime(qs, "updated_since", v)
+
+	return q
+}
+
+func (app *application) listFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.FootballerQuery
+		Engine string
+		Fields []string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.FootballerQuery = app.readFootballerQuery(qs, v)
+
+	input.Engine = app.readString(qs, "engine", "postgres")
+	v.CheckCode(validator.In(input.Engine, "postgres", "es"), "engine", "invalid", "must be postgres or es")
+
+	input.Fields = app.readCSV(qs, "fields", []string{})
+	data.ValidateFields(v, input.Fields)
+	imperial := app.readImperial(qs, v)
+	timeFormat := app.readTimeFormat(qs, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.pagination.defaultPageSize, v)
+	input.Filters.MaxPageSize = app.config.pagination.maxPageSize
+	input.Filters.MaxOffset = app.config.pagination.maxOffset
+
+	input.Filters.Sort = app.readString(qs, "sort", app.config.defaultSort)
+
+	input.Filters.SortSafelist = data.FootballerSortSafelist
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Only PostgreSQL results carry a modification time; search engine results
+	// are always sent in full.
+	if input.Engine == "postgres" {
+		lastModified, err := app.footballers(r).LastModified()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !lastModified.IsZero() {
+			w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+			if notModifiedSince(r, lastModified) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
+	var footballers []*data.Footballer
+	var metadata data.Metadata
+	var err error
+
+	if input.Engine == "es" {
+		footballers, metadata, err = app.searchFootballers(r, input.Name, input.Filters)
+	} else {
+		footballers, metadata, err = app.footballers(r).GetAll(input.FootballerQuery, input.Filters)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, search.ErrNotConfigured):
+			v.AddErrorCode("engine", "not_configured", "search engine is not configured")
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for _, footballer := range footballers {
+		if imperial {
+			footballer.ToImperial()
+		}
+		footballer.TimeFormat = timeFormat
+	}
+
+	var output interface{} = footballers
+	if len(input.Fields) > 0 {
+		selected := make([]map[string]interface{}, 0, len(footballers))
+		for _, footballer := range footballers {
+			selected = append(selected, footballer.Select(input.Fields))
+		}
+		output = selected
+	}
+
+	metadata.Links = app.paginationLinks(r, metadata)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballers", output, metadata), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+func (app *application) randomFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	club := app.readString(qs, "club", "")
+	position := app.readCSV(qs, "positions", []string{})
+	data.NormalizePositions(position)
+
+	footballer, err := app.footballers(r).GetRandom(club, position, app.config.random.tablesample)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// bulkAddPositionHandler adds one position to many footballers at once, e.g.
+// to tag a whole squad with a new tactical role.
+func (app *application) bulkAddPositionHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs      []int64 `json:"ids"`
+		Position string  `json:"position"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Position = strings.ToUpper(strings.TrimSpace(input.Position))
+
+	v := validator.New()
+	v.CheckCode(len(input.IDs) >= 1, "ids", "required", "must be provided")
+	v.CheckCode(len(input.IDs) <= maxBatchIDs, "ids", "too_many", fmt.Sprintf("must not contain more than %d ids", maxBatchIDs))
+	for _, id := range input.IDs {
+		v.CheckCode(id >= 1, "ids", "invalid", "must contain only positive ids")
+	}
+	v.CheckCode(input.Position != "", "position", "required", "must be provided")
+	v.CheckCode(input.Position == "" || data.PositionRole(input.Position) != "", "position", "invalid", "invalid position value")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	modified, err := app.footballers(r).AddPosition(input.IDs, input.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddErrorCode("ids", "not_found", err.Error())
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrTooManyPositions):
+			v.AddErrorCode("position", "too_many", fmt.Sprintf("%s, at most %d are allowed", err, data.MaxPositions))
+			app.failedValidationResponse(w, r, v)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("result", map[string]int64{"modified": modified}, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// maxBatchIDs caps how many footballers one batch request can fetch.
+const maxBatchIDs = 100
+
+// batchGetFootballersHandler returns the footballers with the given ids in the
+// order they were requested. Ids that don't exist are listed in meta.missing
+// instead of failing the whole request.
+func (app *application) batchGetFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	ids := app.readIDs(r.URL.Query(), "ids", v)
+	v.CheckCode(len(ids) >= 1, "ids", "required", "must be provided")
+	v.CheckCode(len(ids) <= maxBatchIDs, "ids", "too_many", fmt.Sprintf("must not contain more than %d ids", maxBatchIDs))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	found, err := app.footballers(r).GetMany(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	byID := make(map[int64]*data.Footballer, len(found))
+	for _, footballer := range found {
+		byID[footballer.ID] = footballer
+	}
+
+	footballers := make([]*data.Footballer, 0, len(ids))
+	missing := []int64{}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if footballer, ok := byID[id]; ok {
+			footballers = append(footballers, footballer)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballers", footballers, envelope{"missing": missing}), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) compareFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	ids := app.readIDs(r.URL.Query(), "ids", v)
+	v.CheckCode(len(ids) == 2, "ids", "invalid", "must contain exactly 2 ids")
+	v.CheckCode(len(ids) != 2 || ids[0] != ids[1], "ids", "duplicate", "must not contain duplicate values")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	footballers, err := app.footballers(r).GetMany(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(footballers) != 2 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Keep the order the ids were requested in.
+	first, second := footballers[0], footballers[1]
+	if first.ID != ids[0] {
+		first, second = second, first
+	}
+
+	comparison := map[string]interface{}{
+		"footballers": []*data.Footballer{first, second},
+		"diff": map[string]int{
+			"goals":         first.Goals - second.Goals,
+			"titles":        first.Titles - second.Titles,
+			"career_length": int(first.Year-first.StartedPlayYear) - int(second.Year-second.StartedPlayYear),
+		},
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("comparison", comparison, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// cloneFootballerHandler creates a new footballer from an existing one. The
+// optional body overrides fields the same way a PATCH does. Like a create, it
+// answers 409 if a footballer with the resulting name and club exists, unless
+// ?force=true is given, so the name or club usually has to change.
+func (app *application) cloneFootballerHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	empty, err := app.bodyIsEmpty(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if !empty {
+		err = app.readFootballerPatch(w, r, footballer)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	data.NormalizeFootballer(footballer)
+
+	v := validator.New()
+	if app.validateFootballer(r, v, footballer); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if r.URL.Query().Get("force") != "true" {
+		exists, err := app.footballers(r).ExistsByNameAndClub(footballer.Name, footballer.Club)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if exists {
+			app.duplicateFootballerResponse(w, r)
+			return
+		}
+	}
+
+	err = app.footballers(r).Clone(footballer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateFootballer):
+			app.uniqueFootballerResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.indexFootballer(footballer)
+	app.events.Publish("created", footballer)
+
+	if app.webhooks != nil {
+		app.webhooks.Enqueue("footballer.created", footballer)
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", app.absoluteURL(r, fmt.Sprintf("/v1/footballer/%d", footballer.ID)))
+
+	err = app.writeJSON(w, r, http.StatusCreated, app.dataEnvelope("footballer", footballer, nil), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// similarFootballersHandler recommends footballers who share positions with
+// the given one and have comparable goals and titles.
+func (app *application) similarFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	limit := app.readInt(r.URL.Query(), "limit", 5, v)
+	if v.CheckCode(limit >= 1 && limit <= data.MaxSimilar, "limit", "out_of_range", fmt.Sprintf("must be between 1 and %d", data.MaxSimilar)); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	similar, err := app.footballers(r).FindSimilar(footballer, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballers", similar, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// teammatesHandler lists the footballers who share a club with the given one,
+// with the shared club in meta.
+func (app *application) teammatesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	teammates, err := app.footballers(r).Teammates(footballer.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballers", teammates, map[string]string{"club": footballer.Club}), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// footballerHistoryHandler returns the audit trail of a footballer. Entries are
+// kept after the footballer is deleted, so no existence check is made.
+func (app *application) footballerHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	entries, err := app.models.Audit.GetForFootballer(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("history", entries, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showFootballerByNameHandler looks a footballer up by exact name. When several
+// footballers share the name it responds 300 Multiple Choices with the
+// candidates so the client can pick one by id.
+func (app *application) showFootballerByNameHandler(w http.ResponseWriter, r *http.Request) {
+	// httprouter matches against the decoded path, so the parameter is
+	// already URL-decoded.
+	name := httprouter.ParamsFromContext(r.Context()).ByName("name")
+
+	footballers, err := app.footballers(r).GetByName(name)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	switch len(footballers) {
+	case 0:
+		app.notFoundResponse(w, r)
+	case 1:
+		err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballers[0], nil), nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	default:
+		env := envelope{
+			"error":      "several footballers share this name, pick one by id",
+			"candidates": footballers,
+		}
+		err = app.writeJSON(w, r, http.StatusMultipleChoices, env, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+// searchFootballers looks up matching ids in the search engine and loads the
+// records from PostgreSQL, keeping the engine's relevance order. Only the name
+// query and pagination apply; the other list filters and sort are ignored.
+func (app *application) searchFootballers(r *http.Request, name string, filters data.Filters) ([]*data.Footballer, data.Metadata, error) {
+	ids, total, err := app.search.Search(name, (filters.Page-1)*filters.PageSize, filters.PageSize)
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	found, err := app.footballers(r).GetMany(ids)
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	byID := make(map[int64]*data.Footballer, len(found))
+	for _, footballer := range found {
+		byID[footballer.ID] = footballer
+	}
+
+	footballers := make([]*data.Footballer, 0, len(ids))
+	for _, id := range ids {
+		if footballer, ok := byID[id]; ok {
+			footballers = append(footballers, footballer)
+		}
+	}
+
+	return footballers, data.CalculateMetadata(total, filters.Page, filters.PageSize), nil
+}
+
+// recentFootballersHandler lists the newest footballers, for callers that
+// don't need the filters and paging of the full list.
+func (app *application) recentFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	limit := app.readInt(r.URL.Query(), "limit", 5, v)
+	if v.CheckCode(limit >= 1 && limit <= data.MaxRecent, "limit", "out_of_range", fmt.Sprintf("must be between 1 and %d", data.MaxRecent)); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	footballers, err := app.footballers(r).Recent(limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballers", footballers, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// careerExtremesHandler returns the footballers with the earliest and latest
+// career starts and the longest career.
+func (app *application) careerExtremesHandler(w http.ResponseWriter, r *http.Request) {
+	extremes, err := app.footballers(r).CareerExtremes()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("extremes", extremes, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) countFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	q := app.readFootballerQuery(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	count, err := app.footballers(r).Count(q)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"count": count}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateFootballer runs the footballer validation rules plus two sanity
+// checks that clients can skip for genuine exceptions: titles per club
+// (?allow_outliers=true) and goalkeepers with outfield positions
+// (?allow_mixed=true).
+func (app *application) validateFootballer(r *http.Request, v *validator.Validator, footballer *data.Footballer) {
+	data.ValidateFootballer(v, footballer)
+
+	allowOutliers := app.readBool(r.URL.Query(), "allow_outliers", v)
+	if allowOutliers == nil || !*allowOutliers {
+		data.ValidateTitlesPerClub(v, footballer, app.config.validation.maxTitlesPerClub)
+	}
+
+	allowMixed := app.readBool(r.URL.Query(), "allow_mixed", v)
+	if allowMixed == nil || !*allowMixed {
+		data.ValidatePositionMix(v, footballer)
+	}
+}
+
+// readFootballerPatch applies a partial update where absent and null fields
+// are both left unchanged.
+func (app *application) readFootballerPatch(w http.ResponseWriter, r *http.Request, footballer *data.Footballer) error {
+	var input struct {
+		Name            *string   `json:"name"`
+		Titles          *int      `json:"titles"`
+		StartedPlayYear *int32    `json:"started_play_year"`
+		Year            *int32    `json:"year"`
+		Club            *string   `json:"club"`
+		PlayedClubs     *int      `json:"played_clubs"`
+		Position        []string `json:"position"`
+		Goals           *int      `json:"goals"`
+		PreferredFoot   *string   `json:"preferred_foot"`
+		HeightCm        *int32    `json:"height_cm"`
+		WeightKg        *int32    `json:"weight_kg"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+	if input.Name != nil {
+		footballer.Name = *input.Name
+	}
+	if input.Titles != nil {
+		footballer.Titles = *input.Titles
+	}
+	if input.StartedPlayYear != nil {
+		footballer.StartedPlayYear = *input.StartedPlayYear
+	}
+	if input.Year != nil {
+		footballer.Year = *input.Year
+	}
+	if input.Club != nil {
+		footballer.Club = *input.Club
+	}
+	if input.PlayedClubs != nil {
+		footballer.PlayedClubs = *input.PlayedClubs
+	}
+	if input.Position != nil {
+		footballer.Position = input.Position
+	}
+	if input.Goals != nil {
+		footballer.Goals = *input.Goals
+	}
+	if input.PreferredFoot != nil {
+		footballer.PreferredFoot = *input.PreferredFoot
+	}
+	if input.HeightCm != nil {
+		footballer.HeightCm = input.HeightCm
+	}
+	if input.WeightKg != nil {
+		footballer.WeightKg = input.WeightKg
+	}
+
+	return nil
+}
+
+// isMergePatch reports whether the request body is a JSON Merge Patch
+// (RFC 7386).
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/merge-patch+json"
+}
+
+// readFootballerMergePatch applies a JSON Merge Patch to the footballer. Absent
+// fields are left unchanged and null resets a field to its zero value:
+// preferred_foot, height_cm and weight_kg become null, club becomes "" and
+// titles and goals become 0. Nulling a required field (name, started_play_year,
+// year, played_clubs, position) fails validation.
+func (app *application) readFootballerMergePatch(w http.ResponseWriter, r *http.Request, footballer *data.Footballer) error {
+	var patch map[string]json.RawMessage
+
+	err := app.readJSON(w, r, &patch)
+	if err != nil {
+		return err
+	}
+	if patch == nil {
+		return errors.New("body must be a JSON object")
+	}
+
+	fields := map[string]interface{}{
+		"name":              &footballer.Name,
+		"titles":            &footballer.Titles,
+		"started_play_year": &footballer.StartedPlayYear,
+		"year":              &footballer.Year,
+		"club":              &footballer.Club,
+		"played_clubs":      &footballer.PlayedClubs,
+		"position":          &footballer.Position,
+		"goals":             &footballer.Goals,
+		"preferred_foot":    &footballer.PreferredFoot,
+		"height_cm":         &footballer.HeightCm,
+		"weight_kg":         &footballer.WeightKg,
+	}
+
+	for key, value := range patch {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("body contains unknown key %q", key)
+		}
+
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			target := reflect.ValueOf(field).Elem()
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+
+		err := json.Unmarshal(value, field)
+		if err != nil {
+			return fmt.Errorf("body contains incorrect JSON type for field %q", key)
+		}
+	}
+
+	return nil
+}
+
+// readDryRun reports whether the request asked for ?dry_run=true, in which
+// case create and update validate the input but skip the database write.
+func (app *application) readDryRun(r *http.Request, v *validator.Validator) bool {
+	dryRun := app.readBool(r.URL.Query(), "dry_run", v)
+	return dryRun != nil && *dryRun
+}
+
+// dryRunResponse returns the footballer as it would have been stored.
+func (app *application) dryRunResponse(w http.ResponseWriter, r *http.Request, footballer *data.Footballer) {
+	err := app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, envelope{"dry_run": true}), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readTimeFormat reads ?time=rfc3339|unix, returning an empty string to keep
+// the -time-format default.
+func (app *application) readTimeFormat(qs url.Values, v *validator.Validator) string {
+	format := app.readString(qs, "time", "")
+	v.CheckCode(format == "" || validator.In(format, data.TimeFormats...), "time", "invalid", "must be rfc3339 or unix")
+	return format
+}
+
+// readImperial reports whether the client asked for ?units=imperial. Heights
+// and weights are stored in metric and only converted on output.
+func (app *application) readImperial(qs url.Values, v *validator.Validator) bool {
+	units := app.readString(qs, "units", "metric")
+	v.CheckCode(validator.In(units, "metric", "imperial"), "units", "invalid", "must be metric or imperial")
+	return units == "imperial"
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"piscine/internal/data"
+	"strings"
+	"testing"
+)
+
+// asUser runs handler with user set in the request context, as the
+// authenticate middleware would.
+func asUser(app *application, user *data.User, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, app.contextSetUser(r, user))
+	})
+}
+
+var testUser = &data.User{ID: 1, Name: "Test", Email: "test@example.com", Activated: true}
+
+const messiJSON = `{"name": "Lionel Messi", "titles": 40, "started_play_year": 2004, "year": 2024, "club": "Inter Miami", "played_clubs": 3, "position": ["forward"], "goals": 800}`
+
+func TestCreateFootballerHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid", messiJSON, http.StatusCreated},
+		{"Malformed JSON", `{"name": `, http.StatusBadRequest},
+		{"Unknown field", `{"name": "Lionel Messi", "nickname": "Leo"}`, http.StatusBadRequest},
+		{"Missing name", strings.Replace(messiJSON, `"Lionel Messi"`, `""`, 1), http.StatusUnprocessableEntity},
+		{"No positions", strings.Replace(messiJSON, `["forward"]`, `[]`, 1), http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			handler := asUser(app, testUser, app.createFootballerHandler)
+
+			rr := do(t, handler, http.MethodPost, "/v1/footballer", tt.body, nil)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("got status %d; want %d; body: %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				Data data.Footballer `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Data.ID == 0 || response.Data.Name != "Lionel Messi" {
+				t.Errorf("got footballer %+v; want Lionel Messi with an ID", response.Data)
+			}
+			if location := rr.Header().Get("Location"); !strings.HasSuffix(location, "/v1/footballer/1") {
+				t.Errorf("got Location %q; want it to end in /v1/footballer/1", location)
+			}
+
+			footballer, err := app.models.Footballers.Get(response.Data.ID)
+			if err != nil {
+				t.Fatalf("footballer wasn't stored: %v", err)
+			}
+			if footballer.Club != "Inter Miami" {
+				t.Errorf("got stored club %q; want Inter Miami", footballer.Club)
+			}
+		})
+	}
+}
+
+func TestCreateFootballerHandlerTrimsInput(t *testing.T) {
+	app := newTestApplication(t)
+	handler := asUser(app, testUser, app.createFootballerHandler)
+
+	body := strings.NewReplacer(`"Lionel Messi"`, `" Messi "`, `"Inter Miami"`, `"  Inter   Miami "`).Replace(messiJSON)
+	rr := do(t, handler, http.MethodPost, "/v1/footballer", body, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d; want %d; body: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	footballer, err := app.models.Footballers.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if footballer.Name != "Messi" {
+		t.Errorf("got stored name %q; want %q", footballer.Name, "Messi")
+	}
+	if footballer.Club != "Inter Miami" {
+		t.Errorf("got stored club %q; want %q", footballer.Club, "Inter Miami")
+	}
+}
+
+func TestCreateFootballerHandlerRejectsCareerStartAfterYear(t *testing.T) {
+	app := newTestApplication(t)
+	handler := asUser(app, testUser, app.createFootballerHandler)
+
+	body := strings.NewReplacer(`"started_play_year": 2004`, `"started_play_year": 2020`, `"year": 2024`, `"year": 2015`).Replace(messiJSON)
+	rr := do(t, handler, http.MethodPost, "/v1/footballer", body, nil)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	var response struct {
+		Error map[string]string `json:"error"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error["year"] != "must not be before started_play_year" {
+		t.Errorf("got errors %v; want one for year", response.Error)
+	}
+}
+
+func TestUpdateFootballerHandlerMergePatch(t *testing.T) {
+	height := int32(170)
+
+	tests := []struct {
+		name   string
+		patch  string
+		verify func(t *testing.T, footballer *data.Footballer)
+	}{
+		{
+			name:  "Absent fields are unchanged",
+			patch: `{"goals": 801}`,
+			verify: func(t *testing.T, footballer *data.Footballer) {
+				if footballer.Goals != 801 {
+					t.Errorf("got goals %d; want 801", footballer.Goals)
+				}
+				if footballer.HeightCm == nil || *footballer.HeightCm != height {
+					t.Errorf("got height_cm %v; want %d", footballer.HeightCm, height)
+				}
+				if footballer.PreferredFoot != "left" || footballer.Club != "Inter Miami" {
+					t.Errorf("got preferred_foot %q and club %q; want them unchanged", footballer.PreferredFoot, footballer.Club)
+				}
+			},
+		},
+		{
+			name:  "Null clears",
+			patch: `{"height_cm": null, "preferred_foot": null, "club": null}`,
+			verify: func(t *testing.T, footballer *data.Footballer) {
+				if footballer.HeightCm != nil {
+					t.Errorf("got height_cm %d; want null", *footballer.HeightCm)
+				}
+				if footballer.PreferredFoot != "" || footballer.Club != "" {
+					t.Errorf("got preferred_foot %q and club %q; want both cleared", footballer.PreferredFoot, footballer.Club)
+				}
+				if footballer.Goals != 800 {
+					t.Errorf("got goals %d; want 800", footballer.Goals)
+				}
+			},
+		},
+		{
+			name:  "Values are set",
+			patch: `{"height_cm": 175, "preferred_foot": "right"}`,
+			verify: func(t *testing.T, footballer *data.Footballer) {
+				if footballer.HeightCm == nil || *footballer.HeightCm != 175 {
+					t.Errorf("got height_cm %v; want 175", footballer.HeightCm)
+				}
+				if footballer.PreferredFoot != "right" {
+					t.Errorf("got preferred_foot %q; want right", footballer.PreferredFoot)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			err := app.models.Footballers.Insert(&data.Footballer{
+				Name: "Lionel Messi", Titles: 40, StartedPlayYear: 2004, Year: 2024, Club: "Inter Miami",
+				PlayedClubs: 3, Position: []string{"ST"}, Goals: 800, PreferredFoot: "left", HeightCm: &height,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := httprouter.New()
+			router.Handler(http.MethodPatch, "/v1/footballer/:id", asUser(app, testUser, app.updateFootballerHandler))
+
+			header := http.Header{"Content-Type": {"application/merge-patch+json"}}
+			rr := do(t, router, http.MethodPatch, "/v1/footballer/1", tt.patch, header)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d; want %d; body: %s", rr.Code, http.StatusOK, rr.Body)
+			}
+
+			footballer, err := app.models.Footballers.Get(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.verify(t, footballer)
+		})
+	}
+}
+
+func TestCreateFootballerHandlerDuplicate(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"Same name and club", "/v1/footballer", messiJSON, http.StatusConflict},
+		{"Forced with the same career start", "/v1/footballer?force=true", messiJSON, http.StatusConflict},
+		{"Forced with another career start", "/v1/footballer?force=true", strings.Replace(messiJSON, `"started_play_year": 2004`, `"started_play_year": 2005`, 1), http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			handler := asUser(app, testUser, app.createFootballerHandler)
+
+			rr := do(t, handler, http.MethodPost, "/v1/footballer", messiJSON, nil)
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("got status %d creating the first footballer; want %d", rr.Code, http.StatusCreated)
+			}
+
+			rr = do(t, handler, http.MethodPost, tt.target, tt.body, nil)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d; body: %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+		})
+	}
+}
+
+func TestFootballerPositionsAreCanonicalized(t *testing.T) {
+	app := newTestApplication(t)
+
+	create := asUser(app, testUser, app.createFootballerHandler)
+	body := strings.Replace(messiJSON, `["forward"]`, `["st", " Cm"]`, 1)
+	rr := do(t, create, http.MethodPost, "/v1/footballer", body, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d; want %d; body: %s", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	footballer, err := app.models.Footballers.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(footballer.Position, ",") != "ST,CM" {
+		t.Errorf("got stored positions %v; want [ST CM]", footballer.Position)
+	}
+
+	list := asUser(app, testUser, app.listFootballerHandler)
+	rr = do(t, list, http.MethodGet, "/v1/footballer?positions=St,cm", "", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d listing; want %d; body: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	var response struct {
+		Data []data.Footballer `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 1 {
+		t.Errorf("got %d footballers for mixed-case positions; want 1", len(response.Data))
+	}
+}
+
+func TestCloneFootballerHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"Empty body", "/v1/footballer/1/clone", "", http.StatusConflict},
+		{"Same name and club", "/v1/footballer/1/clone", `{"started_play_year": 2005}`, http.StatusConflict},
+		{"Forced", "/v1/footballer/1/clone?force=true", `{"started_play_year": 2005}`, http.StatusCreated},
+		{"Another club", "/v1/footballer/1/clone", `{"club": "Barcelona"}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			rr := do(t, asUser(app, testUser, app.createFootballerHandler), http.MethodPost, "/v1/footballer", messiJSON, nil)
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("got status %d creating the original; want %d", rr.Code, http.StatusCreated)
+			}
+
+			router := httprouter.New()
+			router.Handler(http.MethodPost, "/v1/footballer/:id/clone", asUser(app, testUser, app.cloneFootballerHandler))
+
+			// Send the body chunked, so Content-Length is unknown.
+			r := httptest.NewRequest(http.MethodPost, tt.target, io.MultiReader(strings.NewReader(tt.body)))
+			r.ContentLength = -1
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, r)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d; body: %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+		})
+	}
+}
+
+func TestFootballerETag(t *testing.T) {
+	app := newTestApplication(t)
+	rr := do(t, asUser(app, testUser, app.createFootballerHandler), http.MethodPost, "/v1/footballer", messiJSON, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("got status %d creating the footballer; want %d", rr.Code, http.StatusCreated)
+	}
+
+	router := httprouter.New()
+	router.Handler(http.MethodGet, "/v1/footballer/:id", asUser(app, testUser, app.showFootballerHandler))
+	router.Handler(http.MethodHead, "/v1/footballer/:id", asUser(app, testUser, app.existsFootballerHandler))
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantETag   string
+	}{
+		{http.MethodGet, "/v1/footballer/1", http.StatusOK, `"1"`},
+		{http.MethodHead, "/v1/footballer/1", http.StatusOK, `"1"`},
+		{http.MethodHead, "/v1/footballer/2", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rr := do(t, router, tt.method, tt.path, "", nil)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+			if etag := rr.Header().Get("ETag"); etag != tt.wantETag {
+				t.Errorf("got ETag %q; want %q", etag, tt.wantETag)
+			}
+		})
+	}
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"piscine/internal/data"
 	"piscine/internal/validator"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func (app *application) readIDParam(r *http.Request) (int64, error) {
@@ -24,8 +28,37 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 
 type envelope map[string]interface{}
 
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	js, err := json.MarshalIndent(data, "", "\t")
+// dataEnvelope wraps a response payload as {"data": ..., "meta": ...}, leaving
+// out "meta" when it is nil. With -legacy-envelope the payload is keyed by the
+// resource name instead (e.g. "footballer") and meta is sent as "metadata".
+func (app *application) dataEnvelope(key string, data interface{}, meta interface{}) envelope {
+	if app.config.legacyEnvelope {
+		env := envelope{key: data}
+		if meta != nil {
+			env["metadata"] = meta
+		}
+		return env
+	}
+
+	env := envelope{"data": data}
+	if meta != nil {
+		env["meta"] = meta
+	}
+	return env
+}
+
+// writeJSON sends compact JSON by default. Clients can ask for two-space
+// indented output with the ?pretty=true query parameter. The Content-Type is
+// application/json unless headers sets another one.
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
+	var js []byte
+	var err error
+
+	if r.URL.Query().Get("pretty") == "true" {
+		js, err = json.MarshalIndent(data, "", "  ")
+	} else {
+		js, err = json.Marshal(data)
+	}
 
 	if err != nil {
 		return err
@@ -37,7 +70,9 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 		w.Header()[key] = value
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
 
 	w.WriteHeader(status)
 
@@ -88,6 +123,26 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	return nil
 }
 
+// bodyIsEmpty reports whether the request has no body, reading ahead rather
+// than trusting Content-Length, which is -1 for a chunked body. The bytes read
+// are put back for the handler to decode.
+func (app *application) bodyIsEmpty(r *http.Request) (bool, error) {
+	body := bufio.NewReader(r.Body)
+	_, err := body.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+	return false, nil
+}
+
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
 	s := qs.Get(key)
 
@@ -116,19 +171,249 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 	i, err := strconv.Atoi(s)
 	if err != nil {
-		v.AddError(key, "must be an integer value")
+		v.AddErrorCode(key, "invalid_format", "must be an integer value")
 		return defaultValue
 	}
 	return i
 }
 
+// readIDs parses a comma-separated list of positive integer ids.
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	values := app.readCSV(qs, key, []string{})
+
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || id < 1 {
+			v.AddErrorCode(key, "invalid_format", "must be a comma-separated list of positive integer ids")
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// readBool returns nil when the key is absent, so callers can tell "not
+// filtered" apart from false.
+func (app *application) readBool(qs url.Values, key string, v *validator.Validator) *bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddErrorCode(key, "invalid_format", "must be a boolean value")
+		return nil
+	}
+	return &b
+}
+
+// readTime parses an RFC3339 timestamp, returning nil when the key is absent.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddErrorCode(key, "invalid_format", "must be an RFC3339 timestamp")
+		return nil
+	}
+	return &t
+}
+
+// footballers returns the footballer store with writes attributed to the
+// authenticated user in the audit log and queries bound to the request
+// context, so they stop when the client goes away or the request times out.
+func (app *application) footballers(r *http.Request) data.FootballerStore {
+	return app.models.Footballers.WithActor(app.contextGetUser(r).ID).WithContext(r.Context())
+}
+
+// indexFootballer mirrors a footballer into the search index in the background.
+func (app *application) indexFootballer(footballer *data.Footballer) {
+	app.background(func() {
+		err := app.search.Index(footballer)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"footballer_id": strconv.FormatInt(footballer.ID, 10)})
+		}
+	})
+}
+
+func (app *application) unindexFootballer(id int64) {
+	app.background(func() {
+		err := app.search.Delete(id)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"footballer_id": strconv.FormatInt(id, 10)})
+		}
+	})
+}
+
+// indexFootballerIDs mirrors footballers written in bulk into the search index
+// in the background, loading them maxBatchIDs at a time.
+func (app *application) indexFootballerIDs(ids []int64) {
+	app.background(func() {
+		for len(ids) > 0 {
+			batch := ids
+			if len(batch) > maxBatchIDs {
+				batch = batch[:maxBatchIDs]
+			}
+			ids = ids[len(batch):]
+
+			footballers, err := app.models.Footballers.GetMany(batch)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				return
+			}
+			for _, footballer := range footballers {
+				err := app.search.Index(footballer)
+				if err != nil {
+					app.logger.PrintError(err, map[string]string{"footballer_id": strconv.FormatInt(footballer.ID, 10)})
+				}
+			}
+		}
+	})
+}
+
+// clientIP returns the IP address of the client. X-Forwarded-For and X-Real-IP
+// are only honoured when the request comes from a trusted proxy; otherwise
+// anyone could spoof them to dodge the rate limiter.
+func (app *application) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !app.isTrustedProxy(ip) {
+		return ip
+	}
+
+	// Walk X-Forwarded-For from the right, skipping our own proxies, so the
+	// first untrusted hop is the real client.
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !app.isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return ip
+}
+
+// baseURL returns the -base-url setting or, when it isn't set, the scheme and
+// host the client used to reach the API. The X-Forwarded-Proto and
+// X-Forwarded-Host headers are only honoured from trusted proxies.
+func (app *application) baseURL(r *http.Request) *url.URL {
+	if app.config.baseURL != nil {
+		u := *app.config.baseURL
+		return &u
+	}
+
+	u := &url.URL{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if app.isTrustedProxy(ip) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			u.Scheme = proto
+		}
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			u.Host = host
+		}
+	}
+
+	return u
+}
+
+// absoluteURL resolves path against the base URL, for links such as the
+// Location header and those in emails that clients follow as-is. Every
+// generated link should go through it, so that they all honour -base-url.
+func (app *application) absoluteURL(r *http.Request, path string) string {
+	u := app.baseURL(r)
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u.String()
+}
+
+// notModifiedSince reports whether the request has an If-Modified-Since header
+// that is no earlier than lastModified, at the one second resolution of HTTP
+// dates.
+func notModifiedSince(r *http.Request, lastModified time.Time) bool {
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// paginationLinks returns the navigation links for a page of results, keeping
+// every other query parameter of the request, or nil if there are no results.
+func (app *application) paginationLinks(r *http.Request, metadata data.Metadata) *data.Links {
+	if metadata.TotalRecords == 0 {
+		return nil
+	}
+
+	pageURL := func(page int) string {
+		qs := r.URL.Query()
+		qs.Set("page", strconv.Itoa(page))
+
+		return app.absoluteURL(r, r.URL.Path) + "?" + qs.Encode()
+	}
+
+	links := &data.Links{
+		Self:  pageURL(metadata.CurrentPage),
+		First: pageURL(metadata.FirstPage),
+		Last:  pageURL(metadata.LastPage),
+	}
+	if metadata.CurrentPage < metadata.LastPage {
+		links.Next = pageURL(metadata.CurrentPage + 1)
+	}
+	if metadata.CurrentPage > metadata.FirstPage {
+		links.Prev = pageURL(metadata.CurrentPage - 1)
+	}
+
+	return links
+}
+
+func (app *application) isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range app.config.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
 func (app *application) background(fn func()) {
 
 	app.wg.Add(1)
+	app.jobs.pending.Add(1)
 
 	go func() {
 
 		defer app.wg.Done()
+		defer app.jobs.pending.Add(-1)
 		defer func() {
 			if err := recover(); err != nil {
 				app.logger.PrintError(fmt.Errorf("%s", err), nil)
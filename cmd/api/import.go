@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"piscine/internal/data"
+	"piscine/internal/i18n"
+	"piscine/internal/validator"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxImportBytes caps the size of an import upload. The file is parsed as it
+// is read, so this bounds the request rather than memory use.
+const maxImportBytes = 10 << 20
+
+// importEncodings are the character sets accepted by ?encoding= on import.
+// latin1 is decoded as Windows-1252, which is what spreadsheet tools write
+// under that name, and which agrees with ISO 8859-1 on every printable
+// character the latter has.
+var importEncodings = []string{"utf-8", "latin1"}
+
+// maxImportErrors caps how many failed rows are described in an import
+// response; the failed count still covers all of them.
+const maxImportErrors = 100
+
+// importColumns are the CSV header names accepted by the import endpoint,
+// matching the JSON field names. Positions are separated by "|".
+var importColumns = []string{
+	"name", "titles", "started_play_year", "year", "club", "played_clubs",
+	"position", "goals", "preferred_foot", "height_cm", "weight_kg",
+}
+
+type importRowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+// importFootballersHandler creates footballers from the "file" part of a
+// multipart CSV upload. The first line names the columns, in any order; name,
+// club and position are required. Rows that fail validation are reported by
+// line number and skipped, and the rest are written in a single transaction.
+// With ?mode=upsert, a row whose name and club match an existing footballer
+// updates it instead of being reported as a duplicate. ?delimiter= and
+// ?encoding= describe files saved by spreadsheet tools in other locales; a
+// leading UTF-8 byte order mark, as written by Excel, is skipped.
+func (app *application) importFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	mode := app.readString(qs, "mode", "insert")
+	v.CheckCode(validator.In(mode, "insert", "upsert"), "mode", "invalid", "must be insert or upsert")
+
+	delimiter := app.readString(qs, "delimiter", ",")
+	comma, size := utf8.DecodeRuneInString(delimiter)
+	v.CheckCode(size == len(delimiter) && comma != utf8.RuneError && comma != '"' && comma != '\r' && comma != '\n', "delimiter", "invalid", "must be a single character, not a quote or newline")
+
+	encoding := strings.ToLower(app.readString(qs, "encoding", "utf-8"))
+	v.CheckCode(validator.In(encoding, importEncodings...), "encoding", "invalid", "must be utf-8 or latin1")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var file io.Reader
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if part.FormName() == "file" {
+			file = part
+			break
+		}
+	}
+	if file == nil {
+		v.AddErrorCode("file", "required", "must be provided")
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if encoding == "latin1" {
+		file = &windows1252Reader{r: file}
+	} else {
+		file = skipBOM(file)
+	}
+
+	cr := csv.NewReader(file)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !validator.In(name, importColumns...) {
+			v.AddErrorCode("file", "unknown_column", fmt.Sprintf("unknown column %q", name))
+			continue
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "club", "position"} {
+		if _, ok := columns[name]; !ok {
+			v.AddErrorCode("file", "missing_column", fmt.Sprintf("must have a %s column", name))
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	lang := i18n.PreferredLanguage(r.Header.Get("Accept-Language"))
+
+	var inserted, updated, failed int
+	rowErrors := []importRowError{}
+	fail := func(row int, errs map[string]string) {
+		failed++
+		if len(rowErrors) >= maxImportErrors {
+			return
+		}
+		translated := make(map[string]string, len(errs))
+		for key, message := range errs {
+			translated[key] = i18n.Translate(lang, message)
+		}
+		rowErrors = append(rowErrors, importRowError{Row: row, Errors: translated})
+	}
+
+	rows := make(map[*data.Footballer]int)
+	written := []int64{}
+
+	next := func() (*data.Footballer, error) {
+		for {
+			record, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			row, _ := cr.FieldPos(0)
+
+			rv := validator.New()
+			footballer := readImportRecord(record, columns, rv)
+			if rv.Valid() {
+				data.NormalizeFootballer(footballer)
+				app.validateFootballer(r, rv, footballer)
+			}
+			if !rv.Valid() {
+				fail(row, rv.Errors)
+				continue
+			}
+
+			rows[footballer] = row
+			return footballer, nil
+		}
+	}
+
+	done := func(footballer *data.Footballer, wasUpdated bool, err error) {
+		row := rows[footballer]
+		delete(rows, footballer)
+
+		switch {
+		case err != nil:
+			fail(row, map[string]string{"name": "a footballer with this name and club already exists"})
+		case wasUpdated:
+			updated++
+			written = append(written, footballer.ID)
+		default:
+			inserted++
+			written = append(written, footballer.ID)
+		}
+	}
+
+	err = app.footballers(r).Import(mode == "upsert", next, done)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		var parseError *csv.ParseError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxImportBytes))
+		case errors.As(err, &parseError):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.indexFootballerIDs(written)
+
+	result := map[string]interface{}{
+		"inserted": inserted,
+		"updated":  updated,
+		"failed":   failed,
+		"errors":   rowErrors,
+	}
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("result", result, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// skipBOM returns a reader for r without its leading UTF-8 byte order mark,
+// if it has one.
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	return br
+}
+
+// windows1252 maps the bytes 0x80 to 0x9f, where Windows-1252 differs from
+// ISO 8859-1, to their characters. Unassigned bytes map to themselves.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// windows1252Reader decodes Windows-1252 text from r into UTF-8.
+type windows1252Reader struct {
+	r       io.Reader
+	raw     [512]byte
+	pending []byte
+	err     error
+}
+
+func (d *windows1252Reader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		var n int
+		n, d.err = d.r.Read(d.raw[:])
+		for _, b := range d.raw[:n] {
+			c := rune(b)
+			if b >= 0x80 && b < 0xa0 {
+				c = windows1252[b-0x80]
+			}
+			d.pending = utf8.AppendRune(d.pending, c)
+		}
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// readImportRecord converts a CSV record into a footballer, recording fields
+// that aren't numbers where one is expected in v.
+func readImportRecord(record []string, columns map[string]int, v *validator.Validator) *data.Footballer {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	integer := func(name string) int {
+		s := field(name)
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			v.AddErrorCode(name, "invalid_format", "must be an integer value")
+		}
+		return n
+	}
+
+	optional := func(name string) *int32 {
+		if field(name) == "" {
+			return nil
+		}
+		n := int32(integer(name))
+		return &n
+	}
+
+	footballer := &data.Footballer{
+		Name:            field("name"),
+		Titles:          integer("titles"),
+		StartedPlayYear: int32(integer("started_play_year")),
+		Year:            int32(integer("year")),
+		Club:            field("club"),
+		PlayedClubs:     integer("played_clubs"),
+		Goals:           integer("goals"),
+		PreferredFoot:   field("preferred_foot"),
+		HeightCm:        optional("height_cm"),
+		WeightKg:        optional("weight_kg"),
+	}
+	if positions := field("position"); positions != "" {
+		footballer.Position = strings.Split(positions, "|")
+	}
+	return footballer
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// jobStatus is reported by /v1/status. Every field is updated atomically, as
+// the jobs and the status handler run on different goroutines.
+type jobStatus struct {
+	// pending counts running background goroutines, including long-lived
+	// workers such as the token cleanup loop and the webhook dispatcher.
+	pending atomic.Int64
+
+	tokenCleanupLastRun    atomic.Int64 // Unix seconds, 0 until the first run
+	tokenCleanupLastFailed atomic.Bool
+}
+
+// startTokenCleanup periodically deletes expired tokens until stop is closed.
+// It runs as a background task so graceful shutdown waits for it to finish.
+func (app *application) startTokenCleanup(stop <-chan struct{}) {
+	if app.config.jobs.tokenCleanupInterval <= 0 {
+		return
+	}
+
+	app.background(func() {
+		ticker := time.NewTicker(app.config.jobs.tokenCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if app.readOnly.Load() {
+					continue
+				}
+				deleted, err := app.models.Tokens.DeleteExpired()
+				app.jobs.tokenCleanupLastRun.Store(time.Now().Unix())
+				app.jobs.tokenCleanupLastFailed.Store(err != nil)
+				if err != nil {
+					app.logger.PrintError(err, nil)
+					continue
+				}
+				app.logger.PrintInfo("expired tokens removed", map[string]string{
+					"count": strconv.FormatInt(deleted, 10),
+				})
+			}
+		}
+	})
+}
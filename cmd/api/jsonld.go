@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"piscine/internal/data"
+	"strconv"
+	"strings"
+)
+
+// toJSONLD describes a footballer as a schema.org Person, with the current
+// club as its affiliation. Footballers have no nationality yet, so it is not
+// mapped. id is the absolute URL of the footballer.
+func toJSONLD(f *data.Footballer, id string) map[string]interface{} {
+	person := map[string]interface{}{
+		"@context":   "https://schema.org",
+		"@type":      "Person",
+		"@id":        id,
+		"identifier": f.ID,
+		"name":       f.Name,
+		"jobTitle":   "Footballer",
+	}
+
+	if f.Club != "" {
+		person["affiliation"] = map[string]interface{}{
+			"@type": "SportsTeam",
+			"name":  f.Club,
+			"sport": "Football",
+		}
+	}
+
+	if f.HeightCm != nil {
+		person["height"] = map[string]interface{}{
+			"@type":    "QuantitativeValue",
+			"value":    *f.HeightCm,
+			"unitCode": "CMT",
+		}
+	}
+	if f.WeightKg != nil {
+		person["weight"] = map[string]interface{}{
+			"@type":    "QuantitativeValue",
+			"value":    *f.WeightKg,
+			"unitCode": "KGM",
+		}
+	}
+
+	return person
+}
+
+// showFootballerJSONLDHandler serves GET /v1/footballer/:id.jsonld. The route
+// is shared with showFootballerHandler, since httprouter parameters can't
+// carry a suffix.
+func (app *application) showFootballerJSONLDHandler(w http.ResponseWriter, r *http.Request) {
+	param := strings.TrimSuffix(httprouter.ParamsFromContext(r.Context()).ByName("id"), ".jsonld")
+
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/ld+json")
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope(toJSONLD(footballer, app.absoluteURL(r, fmt.Sprintf("/v1/footballer/%d", footballer.ID)))), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
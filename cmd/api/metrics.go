@@ -0,0 +1,173 @@
+package main
+
+import (
+	"database/sql"
+	"net"
+	"net/http"
+	"piscine/internal/data"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests processed.",
+		}, []string{"method", "path", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to process HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "path"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "footballer"))
+	}
+
+	return m
+}
+
+// registerListCache exposes the list cache hit and miss counts.
+func (m *metrics) registerListCache(cache *data.CachedFootballerStore) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "footballer_list_cache_hits_total",
+			Help: "Footballer list requests served from the cache.",
+		}, func() float64 {
+			hits, _ := cache.Stats()
+			return float64(hits)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "footballer_list_cache_misses_total",
+			Help: "Footballer list requests that had to query the database.",
+		}, func() float64 {
+			_, misses := cache.Stats()
+			return float64(misses)
+		}),
+	)
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (sr *statusRecorder) WriteHeader(statusCode int) {
+	if !sr.wroteHeader {
+		sr.statusCode = statusCode
+		sr.wroteHeader = true
+	}
+	sr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	sr.wroteHeader = true
+	return sr.ResponseWriter.Write(b)
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// observeRequests records request counts and durations, labelled by the
+// router's pattern for the request rather than its URL.
+func (app *application) observeRequests(router *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.metrics == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(sr, r)
+
+		path := routePattern(router, r.Method, r.URL.Path)
+
+		app.metrics.requests.WithLabelValues(r.Method, path, strconv.Itoa(sr.statusCode)).Inc()
+		app.metrics.requestDuration.WithLabelValues(r.Method, path).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern returns the route pattern that matches the request, e.g.
+// "/v1/clubs/:name/stats", so every route parameter is collapsed and the
+// number of label values stays bounded whatever clients send. Requests no
+// route matches are labelled "unmatched".
+func routePattern(router *httprouter.Router, method, path string) string {
+	handle, params, _ := router.Lookup(method, path)
+	if handle == nil {
+		return "unmatched"
+	}
+
+	// Parameters always span a whole segment, but their value may also
+	// appear as a literal segment, so probe which segment each one came from.
+	segments := strings.Split(path, "/")
+	for _, param := range params {
+		for i, segment := range segments {
+			if segment != param.Value {
+				continue
+			}
+			probe := append([]string(nil), segments...)
+			probe[i] = "\x00"
+			if _, probed, _ := router.Lookup(method, strings.Join(probe, "/")); probed.ByName(param.Key) == "\x00" {
+				segments[i] = ":" + param.Key
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// metricsHandler serves the Prometheus exposition format to clients in the
+// -metrics-allow networks only.
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.metrics == nil || !app.metricsAllowed(app.clientIP(r)) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	promhttp.HandlerFor(app.metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+func (app *application) metricsAllowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range app.config.metrics.allow {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRoutePattern(t *testing.T) {
+	router := newTestApplication(t).router()
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/v1/healthcheck", "/v1/healthcheck"},
+		{http.MethodGet, "/v1/footballer/42", "/v1/footballer/:id"},
+		{http.MethodGet, "/v1/footballer/42.jsonld", "/v1/footballer/:id"},
+		{http.MethodGet, "/v1/footballer/7/teammates", "/v1/footballer/:id/teammates"},
+		{http.MethodGet, "/v1/footballers/by-name/Lionel Messi", "/v1/footballers/by-name/:name"},
+		{http.MethodGet, "/v1/clubs/Barcelona/stats", "/v1/clubs/:name/stats"},
+		{http.MethodGet, "/v1/clubs/stats/stats", "/v1/clubs/:name/stats"},
+		{http.MethodGet, "/v1/clubs/v1/stats", "/v1/clubs/:name/stats"},
+		{http.MethodGet, "/v1/nothing-here", "unmatched"},
+		{http.MethodDelete, "/v1/healthcheck", "unmatched"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := routePattern(router, tt.method, tt.path); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
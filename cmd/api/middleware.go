@@ -1,18 +1,31 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"golang.org/x/time/rate"
-	"net"
+	"math"
 	"net/http"
 	"piscine/internal/data"
 	"piscine/internal/validator"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// trackInFlight counts the requests being handled, so shutdown can wait for
+// them to finish.
+func (app *application) trackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.inFlight.Add(1)
+		defer app.inFlight.Add(-1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -29,7 +42,136 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) rateLimit(next http.Handler) http.Handler {
+// enableCORS allows the -cors-trusted-origins to call the API from a browser.
+// The request origin is echoed back rather than sent as "*" whenever it is
+// listed explicitly, which browsers require for credentialed requests.
+// Preflight requests are answered here, before authentication.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			for _, trusted := range app.config.cors.trustedOrigins {
+				if trusted != origin && trusted != "*" {
+					continue
+				}
+
+				if trusted == "*" {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+				if app.config.cors.allowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+
+				if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+					w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, HEAD, POST, PUT, PATCH, DELETE")
+					w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Modified-Since, X-Feature-Flags")
+					if app.config.cors.maxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(app.config.cors.maxAge.Seconds())))
+					}
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				break
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// timeout cancels the request context after -request-timeout. Footballer
+// queries run under the request context (see app.footballers), so a handler
+// that is still querying when the deadline passes has its query canceled and
+// answers 503 through serverErrorResponse. The event stream and NDJSON export
+// are long-lived by design and are exempt, as is the CSV import, which has its
+// own longer deadline.
+func (app *application) timeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case app.config.server.requestTimeout == 0,
+			r.URL.Path == "/v1/footballers/stream",
+			r.URL.Path == "/v1/footballers.ndjson",
+			r.URL.Path == "/v1/footballers/import":
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), app.config.server.requestTimeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// featureFlags reads the comma-separated X-Feature-Flags header, e.g.
+// "fuzzy-search,relevance-sort", into the request context so handlers can
+// enable experimental code paths per client with contextHasFeature.
+func (app *application) featureFlags(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "X-Feature-Flags")
+
+		header := r.Header.Get("X-Feature-Flags")
+		if header != "" {
+			features := make(map[string]bool)
+			for _, name := range strings.Split(header, ",") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name != "" {
+					features[name] = true
+				}
+			}
+			r = app.contextSetFeatures(r, features)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// modeSwitchPaths are the endpoints that turn maintenance and read-only mode
+// on and off, which have to keep working while either mode is on.
+var modeSwitchPaths = []string{"/v1/admin/maintenance", "/v1/admin/read-only"}
+
+// maintenanceMode answers every request that could write with 503 while
+// maintenance or read-only mode is on. Reads, including /v1/healthcheck, keep
+// working, as do the endpoints that switch the modes off again. Other admin
+// writes, such as a reindex, wait like any other write.
+func (app *application) maintenanceMode(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (app.maintenance.Load() || app.readOnly.Load()) && !validator.In(r.URL.Path, modeSwitchPaths...) && !isReadMethod(r.Method) {
+			if app.maintenance.Load() {
+				w.Header().Set("Retry-After", strconv.Itoa(int(app.config.maintenance.retryAfter.Seconds())))
+				app.maintenanceModeResponse(w, r)
+				return
+			}
+			app.readOnlyModeResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isReadMethod reports whether method is one that never writes.
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// rateLimit returns the two halves of the rate limiter. byIP goes in front of
+// authenticate and keys on the client IP, so floods of bad tokens are
+// throttled before they cost a database lookup: anonymous requests take a
+// token from the IP's bucket, while requests carrying a token need one to be
+// left in it and only take it if authentication fails. byUser goes after
+// authenticate and charges authenticated requests to the user's own bucket
+// instead, so users sharing an IP address (e.g. behind NAT) don't throttle
+// each other.
+func (app *application) rateLimit() (byIP, byUser func(http.Handler) http.Handler) {
 	type client struct {
 		limiter  *rate.Limiter
 		lastSeen time.Time
@@ -47,38 +189,100 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 
 			mu.Lock()
 
-			for ip, client := range clients {
+			for key, client := range clients {
 				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+					delete(clients, key)
 				}
 			}
 
 			mu.Unlock()
 		}
 	}()
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.limiter.enabled {
-			ip, _, err := net.SplitHostPort(r.RemoteAddr)
-			if err != nil {
-				app.serverErrorResponse(w, r, err)
+
+	bucket := func(key string, limit rate.Limit, burst int) *rate.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, found := clients[key]; !found {
+			clients[key] = &client{
+				limiter: rate.NewLimiter(limit, burst),
+			}
+		}
+		clients[key].lastSeen = time.Now()
+		return clients[key].limiter
+	}
+
+	// allow reports whether limiter has a token left for the request, taking
+	// it unless peek is set, and writes the rate limit headers. It sends a 429
+	// if there is none.
+	allow := func(w http.ResponseWriter, r *http.Request, limiter *rate.Limiter, peek bool) bool {
+		now := time.Now()
+		allowed := limiter.TokensAt(now) >= 1
+		if !peek {
+			allowed = limiter.AllowN(now, 1)
+		}
+		tokens := limiter.TokensAt(now)
+
+		if app.config.limiter.headers || !allowed {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limiter.Burst()))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+		}
+
+		if !allowed {
+			// Seconds until the bucket refills enough for one more request.
+			retryAfter := math.Ceil((1 - tokens) / float64(limiter.Limit()))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, retryAfter))))
+			app.rateLimitExceededResponse(w, r)
+		}
+		return allowed
+	}
+
+	byIP = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.config.limiter.enabled {
+				next.ServeHTTP(w, r)
 				return
 			}
-			mu.Lock()
-			if _, found := clients[ip]; !found {
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
+
+			limiter := bucket("ip:"+app.clientIP(r), rate.Limit(app.config.limiter.rps), app.config.limiter.burst)
+
+			if r.Header.Get("Authorization") == "" {
+				if allow(w, r, limiter, false) {
+					next.ServeHTTP(w, r)
 				}
+				return
 			}
-			clients[ip].lastSeen = time.Now()
-			if !clients[ip].limiter.Allow() {
-				mu.Unlock()
-				app.rateLimitExceededResponse(w, r)
+
+			if !allow(w, r, limiter, true) {
 				return
 			}
-			mu.Unlock()
-		}
-		next.ServeHTTP(w, r)
-	})
+
+			authenticated := false
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authenticatedContextKey, &authenticated)))
+			if !authenticated {
+				limiter.Allow()
+			}
+		})
+	}
+
+	byUser = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := app.contextGetUser(r)
+			if app.config.limiter.enabled && !user.IsAnonymous() {
+				if authenticated, ok := r.Context().Value(authenticatedContextKey).(*bool); ok {
+					*authenticated = true
+				}
+
+				limiter := bucket(fmt.Sprintf("user:%d", user.ID), rate.Limit(app.config.limiter.userRps), app.config.limiter.userBurst)
+				if !allow(w, r, limiter, false) {
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return byIP, byUser
 }
 
 func (app *application) authenticate(next http.Handler) http.Handler {
@@ -112,7 +316,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
 		if err != nil {
 			switch {
-			case errors.Is(err, data.ErrRecordNotFound):
+			case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrTokenExpired), errors.Is(err, data.ErrTokenUsed):
 				app.invalidAuthenticationTokenResponse(w, r)
 			default:
 				app.serverErrorResponse(w, r, err)
@@ -137,12 +341,15 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 	})
 }
 
+// requireActivatedUser rejects accounts that haven't been activated. With
+// -require-activation-for-reads=false it only does so for requests that could
+// write, so reads are open to any registered account.
 func (app *application) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
 
 	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 
-		if !user.Activated {
+		if !user.Activated && (app.config.auth.requireActivationForReads || !isReadMethod(r.Method)) {
 			app.inactiveAccountResponse(w, r)
 			return
 		}
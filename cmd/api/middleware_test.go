@@ -0,0 +1,214 @@
+package main
+
+import (
+	"net/http"
+	"piscine/internal/data"
+	"strings"
+	"testing"
+	"time"
+)
+
+// okHandler stands in for the router in middleware tests.
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("OK"))
+})
+
+func TestMaintenanceMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"Read", http.MethodGet, "/v1/footballer", http.StatusOK},
+		{"Write", http.MethodPost, "/v1/footballer", http.StatusServiceUnavailable},
+		{"Maintenance toggle", http.MethodPost, "/v1/admin/maintenance", http.StatusOK},
+		{"Read-only toggle", http.MethodPost, "/v1/admin/read-only", http.StatusOK},
+		{"Reindex", http.MethodPost, "/v1/admin/reindex", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.maintenance.Store(true)
+
+			rr := do(t, app.maintenanceMode(okHandler), tt.method, tt.path, "", nil)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestEnableCORSCredentialedPreflight(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.cors.trustedOrigins = []string{"https://app.example.com"}
+	app.config.cors.allowCredentials = true
+	app.config.cors.maxAge = 10 * time.Minute
+
+	header := http.Header{}
+	header.Set("Origin", "https://app.example.com")
+	header.Set("Access-Control-Request-Method", http.MethodPost)
+
+	rr := do(t, app.enableCORS(okHandler), http.MethodOptions, "/v1/footballer", "", header)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("preflight reached the router; got body %q", rr.Body)
+	}
+
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for key, value := range want {
+		if got := rr.Header().Get(key); got != value {
+			t.Errorf("got %s %q; want %q", key, got, value)
+		}
+	}
+
+	methods := rr.Header().Get("Access-Control-Allow-Methods")
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if !strings.Contains(methods, method) {
+			t.Errorf("Access-Control-Allow-Methods %q is missing %s", methods, method)
+		}
+	}
+}
+
+func TestEnableCORSUntrustedOrigin(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.cors.trustedOrigins = []string{"https://app.example.com"}
+	app.config.cors.allowCredentials = true
+
+	header := http.Header{}
+	header.Set("Origin", "https://evil.example.com")
+	header.Set("Access-Control-Request-Method", http.MethodPost)
+
+	rr := do(t, app.enableCORS(okHandler), http.MethodOptions, "/v1/footballer", "", header)
+
+	for _, key := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials"} {
+		if got := rr.Header().Get(key); got != "" {
+			t.Errorf("got %s %q for an untrusted origin; want none", key, got)
+		}
+	}
+}
+
+// newTestUser stores an activated user and returns an authentication token
+// for them.
+func newTestUser(t *testing.T, app *application, email string) string {
+	t.Helper()
+
+	user := &data.User{Name: "Test", Email: email, Activated: true}
+	if err := app.models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.models.Tokens.New(user.ID, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token.Plaintext
+}
+
+func TestRateLimit(t *testing.T) {
+	// With refills this slow every bucket holds exactly its burst for the
+	// length of the test.
+	setup := func(t *testing.T) (*application, http.Handler) {
+		app := newTestApplication(t)
+		app.config.limiter.enabled = true
+		app.config.limiter.rps = 0.001
+		app.config.limiter.userRps = 0.001
+
+		limitByIP, limitByUser := app.rateLimit()
+		return app, limitByIP(app.authenticate(limitByUser(okHandler)))
+	}
+
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+
+	// send makes n requests with header, all from httptest's default client
+	// address, and returns the status of the last one.
+	send := func(t *testing.T, handler http.Handler, n int, header http.Header) int {
+		var status int
+		for i := 0; i < n; i++ {
+			status = do(t, handler, http.MethodGet, "/v1/footballer", "", header).Code
+		}
+		return status
+	}
+
+	t.Run("Anonymous requests share the IP bucket", func(t *testing.T) {
+		app, handler := setup(t)
+
+		if status := send(t, handler, app.config.limiter.burst, nil); status != http.StatusOK {
+			t.Fatalf("got status %d within the burst; want %d", status, http.StatusOK)
+		}
+		if status := send(t, handler, 1, nil); status != http.StatusTooManyRequests {
+			t.Errorf("got status %d past the burst; want %d", status, http.StatusTooManyRequests)
+		}
+	})
+
+	t.Run("Users on one IP get independent buckets", func(t *testing.T) {
+		app, handler := setup(t)
+		alice := newTestUser(t, app, "alice@example.com")
+		bob := newTestUser(t, app, "bob@example.com")
+
+		// The user burst is larger than the IP burst, so this only passes
+		// if authenticated requests stop counting against the IP.
+		if status := send(t, handler, app.config.limiter.userBurst, bearer(alice)); status != http.StatusOK {
+			t.Fatalf("got status %d within alice's burst; want %d", status, http.StatusOK)
+		}
+		if status := send(t, handler, 1, bearer(alice)); status != http.StatusTooManyRequests {
+			t.Errorf("got status %d past alice's burst; want %d", status, http.StatusTooManyRequests)
+		}
+		if status := send(t, handler, app.config.limiter.userBurst, bearer(bob)); status != http.StatusOK {
+			t.Errorf("got status %d for bob after alice was limited; want %d", status, http.StatusOK)
+		}
+		if status := send(t, handler, app.config.limiter.burst, nil); status != http.StatusOK {
+			t.Errorf("got status %d for anonymous requests from the same IP; want %d", status, http.StatusOK)
+		}
+	})
+
+	t.Run("Bad tokens are limited by IP", func(t *testing.T) {
+		app, handler := setup(t)
+		unknown := bearer("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+		if status := send(t, handler, app.config.limiter.burst, unknown); status != http.StatusUnauthorized {
+			t.Fatalf("got status %d within the burst; want %d", status, http.StatusUnauthorized)
+		}
+		if status := send(t, handler, 1, unknown); status != http.StatusTooManyRequests {
+			t.Errorf("got status %d past the burst; want %d", status, http.StatusTooManyRequests)
+		}
+	})
+}
+
+func TestTimeoutExemptions(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantDeadline bool
+	}{
+		{"/v1/footballer", true},
+		{"/v1/footballers/stream", false},
+		{"/v1/footballers.ndjson", false},
+		{"/v1/footballers/import", false},
+	}
+
+	app := newTestApplication(t)
+	app.config.server.requestTimeout = 20 * time.Second
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var hasDeadline bool
+			handler := app.timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, hasDeadline = r.Context().Deadline()
+			}))
+
+			do(t, handler, http.MethodPost, tt.path, "", nil)
+			if hasDeadline != tt.wantDeadline {
+				t.Errorf("got deadline %v; want %v", hasDeadline, tt.wantDeadline)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"piscine/internal/jsonlog"
+	"piscine/migrations"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+type migrateLogger struct {
+	logger *jsonlog.Logger
+}
+
+func (l migrateLogger) Printf(format string, v ...interface{}) {
+	l.logger.PrintInfo(strings.TrimSpace(fmt.Sprintf(format, v...)), nil)
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
+
+// runMigrations applies the embedded SQL migrations. The "up" direction applies
+// every pending migration, while "down" rolls back the most recent one.
+func runMigrations(db *sql.DB, direction string, logger *jsonlog.Logger) error {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return err
+	}
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return err
+	}
+	m.Log = migrateLogger{logger: logger}
+
+	switch direction {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Steps(-1)
+	default:
+		return fmt.Errorf("invalid migration direction %q", direction)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	logger.PrintInfo("database migrations applied", map[string]string{
+		"direction": direction,
+		"version":   fmt.Sprintf("%d", version),
+		"dirty":     fmt.Sprintf("%t", dirty),
+	})
+	return nil
+}
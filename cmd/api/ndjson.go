@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"piscine/internal/data"
+	"piscine/internal/validator"
+)
+
+// listFootballersNDJSONHandler streams every footballer matching the list
+// filters as newline-delimited JSON, one object per line, flushing after each
+// row. There is no envelope and no pagination. Once the first row has been
+// sent, errors can only be logged, so clients should treat a stream that ends
+// early as incomplete.
+func (app *application) listFootballersNDJSONHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		app.serverErrorResponse(w, r, errors.New("streaming unsupported by response writer"))
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	q := app.readFootballerQuery(qs, v)
+
+	fields := app.readCSV(qs, "fields", []string{})
+	data.ValidateFields(v, fields)
+	imperial := app.readImperial(qs, v)
+	timeFormat := app.readTimeFormat(qs, v)
+
+	filters := data.Filters{
+		Sort:         app.readString(qs, "sort", app.config.defaultSort),
+		SortSafelist: data.FootballerSortSafelist,
+	}
+	data.ValidateSort(v, filters.Sort, filters.SortSafelist)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	started := false
+	start := func() {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	err := app.footballers(r).Stream(q, filters, func(footballer *data.Footballer) error {
+		if imperial {
+			footballer.ToImperial()
+		}
+		footballer.TimeFormat = timeFormat
+
+		var row interface{} = footballer
+		if len(fields) > 0 {
+			row = footballer.Select(fields)
+		}
+
+		start()
+		err := enc.Encode(row)
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		if !started {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.logError(r, err)
+		return
+	}
+
+	start()
+}
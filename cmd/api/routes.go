@@ -6,6 +6,15 @@ import (
 )
 
 func (app *application) routes() http.Handler {
+	router := app.router()
+
+	limitByIP, limitByUser := app.rateLimit()
+	return app.trackInFlight(app.compress(app.recoverPanic(app.observeRequests(router, app.enableCORS(app.maintenanceMode(app.timeout(limitByIP(app.authenticate(limitByUser(app.featureFlags(router)))))))))))
+}
+
+// router registers every route. It is separate from routes so the metrics can
+// look up which route pattern a request matched.
+func (app *application) router() *httprouter.Router {
 	router := httprouter.New()
 
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
@@ -13,19 +22,63 @@ func (app *application) routes() http.Handler {
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/status", app.statusHandler)
+	router.HandlerFunc(http.MethodGet, "/metrics", app.metricsHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/openapi.json", app.openAPIHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/docs", app.swaggerUIHandler)
 
 	router.HandlerFunc(http.MethodGet, "/v1/footballer", app.requirePermission("footballers:read", app.listFootballerHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/footballer", app.requirePermission("footballers:write", app.createFootballerHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/footballer/:id", app.requirePermission("footballers:read", app.showFootballerHandler))
+	router.HandlerFunc(http.MethodHead, "/v1/footballer/:id", app.requirePermission("footballers:read", app.existsFootballerHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/footballer/:id", app.requirePermission("footballers:write", app.updateFootballerHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/footballer/:id", app.requirePermission("footballers:write", app.deleteFootballerHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/footballer/:id/recent-goals", app.requirePermission("footballers:write", app.updateRecentGoalsHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/footballer/:id/club", app.requirePermission("footballers:write", app.changeClubHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/footballer/:id/injury", app.requirePermission("footballers:write", app.updateInjuryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballer/:id/verify", app.requirePermission("footballers:verify", app.verifyFootballerHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballer/:id/seasons", app.requirePermission("footballers:read", app.listSeasonsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballer/:id/seasons", app.requirePermission("footballers:write", app.createSeasonHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballer/:id/recompute-goals", app.requirePermission("footballers:write", app.recomputeGoalsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballer/:id/clone", app.requirePermission("footballers:write", app.cloneFootballerHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballer/:id/similar", app.requirePermission("footballers:read", app.similarFootballersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballer/:id/teammates", app.requirePermission("footballers:read", app.teammatesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballer/:id/history", app.requirePermission("audit:read", app.footballerHistoryHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/footballers.ndjson", app.requirePermission("footballers:read", app.listFootballersNDJSONHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/random", app.requirePermission("footballers:read", app.randomFootballerHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/recent", app.requirePermission("footballers:read", app.recentFootballersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/extremes", app.requirePermission("footballers:read", app.careerExtremesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/count", app.requirePermission("footballers:read", app.countFootballersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/batch", app.requirePermission("footballers:read", app.batchGetFootballersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/schema", app.requirePermission("footballers:read", app.footballerSchemaHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/compare", app.requirePermission("footballers:read", app.compareFootballersHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/by-name/:name", app.requirePermission("footballers:read", app.showFootballerByNameHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/footballers/stream", app.requirePermission("footballers:read", app.streamFootballersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballers/bulk-add-position", app.requirePermission("footballers:write", app.bulkAddPositionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballers/merge", app.requirePermission("footballers:merge", app.mergeFootballersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/footballers/import", app.requirePermission("footballers:write", app.importFootballersHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/footballers/goals", app.requirePermission("footballers:write", app.incrementGoalsHandler))
 
+	router.HandlerFunc(http.MethodGet, "/v1/clubs/:name/stats", app.requirePermission("footballers:read", app.clubStatsHandler))
+
+	router.HandlerFunc(http.MethodPost, "/v1/admin/maintenance", app.requirePermission("admin:maintenance", app.maintenanceHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/read-only", app.requirePermission("admin:maintenance", app.readOnlyHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/reindex", app.requirePermission("admin:maintenance", app.reindexHandler))
+
+	if app.config.env == "development" {
+		router.HandlerFunc(http.MethodPost, "/v1/debug/reset", app.resetHandler)
+	}
+
+	router.HandlerFunc(http.MethodGet, "/v1/users", app.requirePermission("users:read", app.listUsersHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/tokens/validate", app.requireAuthenticatedUser(app.validateTokenHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/logout-all", app.requireAuthenticatedUser(app.logoutAllHandler))
 
-	return app.recoverPanic(app.rateLimit(app.authenticate(router)))
-
+	return router
 }
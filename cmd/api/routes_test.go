@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoutesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  []string
+	}{
+		{"Unknown path", http.MethodGet, "/v1/nothing-here", http.StatusNotFound, nil},
+		{"Wrong method", http.MethodDelete, "/v1/healthcheck", http.StatusMethodNotAllowed, []string{http.MethodGet}},
+		{"Wrong method on an id route", http.MethodPost, "/v1/footballer/1", http.StatusMethodNotAllowed, []string{http.MethodGet, http.MethodHead, http.MethodPatch, http.MethodDelete}},
+	}
+
+	app := newTestApplication(t)
+	routes := app.routes()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(t, routes, tt.method, tt.path, "", nil)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantStatus)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("got Content-Type %q; want application/json", contentType)
+			}
+
+			allow := rr.Header().Get("Allow")
+			for _, method := range tt.wantAllow {
+				if !strings.Contains(allow, method) {
+					t.Errorf("Allow %q is missing %s", allow, method)
+				}
+			}
+			if tt.wantAllow == nil && allow != "" {
+				t.Errorf("got Allow %q; want none", allow)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"piscine/internal/data"
+	"piscine/internal/validator"
+)
+
+func (app *application) listSeasonsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	exists, err := app.footballers(r).Exists(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	seasons, err := app.models.Seasons.GetAllForFootballer(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("seasons", seasons, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		SeasonYear int32  `json:"season_year"`
+		Goals      int    `json:"goals"`
+		Club       string `json:"club"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	season := &data.SeasonGoals{
+		FootballerID: id,
+		SeasonYear:   input.SeasonYear,
+		Goals:        input.Goals,
+		Club:         input.Club,
+	}
+
+	v := validator.New()
+	if data.ValidateSeasonGoals(v, season); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	err = app.models.Seasons.Insert(season)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSeason):
+			v.AddErrorCode("season_year", "duplicate", "a season already exists for this year")
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", app.absoluteURL(r, fmt.Sprintf("/v1/footballer/%d/seasons", id)))
+
+	err = app.writeJSON(w, r, http.StatusCreated, app.dataEnvelope("season", season, nil), headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// recomputeGoalsHandler replaces the career goals total with the sum of the
+// recorded seasons.
+func (app *application) recomputeGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.footballers(r).RecomputeGoals(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	footballer, err := app.footballers(r).Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.indexFootballer(footballer)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("footballer", footballer, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
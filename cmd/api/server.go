@@ -3,24 +3,28 @@ package main
 import (
 	"context"
 	"errors"
-	"fmt"
+	"net"
 	"net/http"
 	"os"        // New import
 	"os/signal" // New import
-	"syscall"   // New import
+	"strconv"
+	"syscall" // New import
 	"time"
 )
 
 func (app *application) serve() error {
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", app.config.port),
-		Handler:      app.routes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		Addr:              net.JoinHostPort(app.config.host, strconv.Itoa(app.config.port)),
+		Handler:           app.routes(),
+		IdleTimeout:       app.config.server.idleTimeout,
+		ReadTimeout:       app.config.server.readTimeout,
+		ReadHeaderTimeout: app.config.server.readHeaderTimeout,
+		WriteTimeout:      app.config.server.writeTimeout,
+		MaxHeaderBytes:    app.config.server.maxHeaderBytes,
 	}
 
 	shutdownError := make(chan error)
+	stopJobs := make(chan struct{})
 	go func() {
 
 		quit := make(chan os.Signal, 1)
@@ -33,23 +37,41 @@ func (app *application) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
+		app.events.Close()
+
 		err := srv.Shutdown(ctx)
+		app.drainInFlight(ctx)
 		if err != nil {
 			shutdownError <- err
+			return
 		}
 
 		app.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
 
+		close(stopJobs)
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
+	app.startTokenCleanup(stopJobs)
+
+	if app.webhooks != nil {
+		app.background(func() {
+			app.webhooks.Run(stopJobs)
+		})
+	}
+
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
 	app.logger.PrintInfo("starting server", map[string]string{
-		"addr": srv.Addr,
+		"addr": listener.Addr().String(),
 		"env":  app.config.env,
 	})
-	err := srv.ListenAndServe()
+	err = srv.Serve(listener)
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -62,3 +84,36 @@ func (app *application) serve() error {
 	})
 	return nil
 }
+
+// drainInFlight waits until no request is being handled or ctx is done,
+// logging how many remain once a second. srv.Shutdown already waits for idle
+// connections, but not for hijacked ones or handlers that outlive their
+// connection, so this catches what it leaves behind.
+func (app *application) drainInFlight(ctx context.Context) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	var logged time.Time
+	for {
+		remaining := app.inFlight.Load()
+		if remaining == 0 {
+			return
+		}
+
+		if time.Since(logged) >= time.Second {
+			app.logger.PrintInfo("waiting for in-flight requests", map[string]string{
+				"remaining": strconv.FormatInt(remaining, 10),
+			})
+			logged = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			app.logger.PrintWarn("in-flight requests cut off by shutdown", map[string]string{
+				"remaining": strconv.FormatInt(app.inFlight.Load(), 10),
+			})
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// statusHandler reports the state of the background jobs, so that a stuck
+// queue shows up before it backs up. Like /metrics, it is only served to
+// -metrics-allow addresses.
+func (app *application) statusHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.metricsAllowed(app.clientIP(r)) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	tokenCleanup := map[string]interface{}{
+		"interval": app.config.jobs.tokenCleanupInterval.String(),
+		"last_run": nil,
+		"failed":   app.jobs.tokenCleanupLastFailed.Load(),
+	}
+	if lastRun := app.jobs.tokenCleanupLastRun.Load(); lastRun != 0 {
+		tokenCleanup["last_run"] = time.Unix(lastRun, 0).UTC()
+	}
+
+	status := map[string]interface{}{
+		"maintenance":      app.maintenance.Load(),
+		"read_only":        app.readOnly.Load(),
+		"background_tasks": app.jobs.pending.Load(),
+		"in_flight":        app.inFlight.Load(),
+		"token_cleanup":    tokenCleanup,
+		"stream_clients":   app.events.Subscribers(),
+	}
+
+	if app.webhooks != nil {
+		depth, capacity := app.webhooks.QueueDepth()
+		status["webhook_queue"] = map[string]int{"depth": depth, "capacity": capacity}
+	}
+
+	err := app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("status", status, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"piscine/internal/events"
+	"time"
+)
+
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamFootballersHandler pushes newly created footballers to the client as
+// server-sent events. The connection is still subject to the server write
+// timeout; EventSource clients reconnect automatically after the retry delay.
+func (app *application) streamFootballersHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		app.serverErrorResponse(w, r, errors.New("streaming unsupported by response writer"))
+		return
+	}
+
+	stream, unsubscribe, err := app.events.Subscribe()
+	if err != nil {
+		switch {
+		case errors.Is(err, events.ErrTooManySubscribers):
+			app.tooManySubscribersResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+
+			js, err := json.Marshal(event.Data)
+			if err != nil {
+				app.logError(r, err)
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, js)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
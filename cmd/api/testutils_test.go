@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"piscine/internal/data"
+	"piscine/internal/data/mock"
+	"piscine/internal/events"
+	"piscine/internal/jsonlog"
+	"piscine/internal/search"
+	"testing"
+)
+
+// newTestApplication returns an application backed by the in-memory mock
+// stores, configured with the same defaults as the command line flags.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	var cfg config
+	cfg.env = "development"
+	cfg.timeFormat = data.TimeFormatRFC3339
+	cfg.defaultSort = "id"
+	cfg.pagination.defaultPageSize = 20
+	cfg.pagination.maxPageSize = 100
+	cfg.pagination.maxOffset = 10000
+	cfg.validation.maxTitlesPerClub = 20
+	cfg.validation.minPositions = 1
+	cfg.validation.maxPositions = 6
+	cfg.limiter.rps = 2
+	cfg.limiter.burst = 4
+	cfg.limiter.userRps = 4
+	cfg.limiter.userBurst = 8
+	cfg.limiter.headers = true
+	cfg.auth.requireActivationForReads = true
+	cfg.gzip.minBytes = -1
+
+	return &application{
+		config: cfg,
+		logger: jsonlog.New(io.Discard, jsonlog.LevelInfo),
+		models: mock.NewModels(),
+		search: search.NoopIndexer{},
+		events: events.NewHub(10),
+	}
+}
+
+// do sends a request through the application's full middleware chain and
+// returns the recorded response.
+func do(t *testing.T, handler http.Handler, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	for key, values := range header {
+		r.Header[key] = values
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	return rr
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"crypto/sha256"
 	"errors"
 	"net/http"
 	"piscine/internal/data"
 	"piscine/internal/validator"
+	"strings"
 	"time"
 )
 
@@ -24,7 +26,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	data.ValidateEmail(v, input.Email)
 	data.ValidatePasswordPlaintext(v, input.Password)
 	if !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -56,8 +58,53 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, app.dataEnvelope("authentication_token", token, nil), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
-}
\ No newline at end of file
+}
+// logoutAllHandler revokes every authentication token of the current user,
+// including the one used for this request, and reports how many were revoked.
+func (app *application) logoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	revoked, err := app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"revoked": revoked}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateTokenHandler reports the scope, owner and expiry of the bearer token
+// used for the request, without using it up. Invalid and expired tokens are
+// already answered with 401 by the authenticate middleware.
+func (app *application) validateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	plaintext := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	hash := sha256.Sum256([]byte(plaintext))
+
+	token, err := app.models.Tokens.Peek(data.ScopeAuthentication, hash[:])
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrTokenExpired), errors.Is(err, data.ErrTokenUsed):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := map[string]interface{}{
+		"scope":   token.Scope,
+		"user_id": token.UserID,
+		"expiry":  token.Expiry,
+	}
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("token", env, nil), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -36,7 +36,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	v := validator.New()
 
 	if data.ValidateUser(v, user); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -44,8 +44,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
-			v.AddError("email", "a user with this email address already exists")
-			app.failedValidationResponse(w, r, v.Errors)
+			v.AddErrorCode("email", "duplicate", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -64,8 +64,11 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	activationURL := app.absoluteURL(r, "/v1/users/activated")
+
 	app.background(func() {
 		data := map[string]interface{}{
+			"activationURL":   activationURL,
 			"activationToken": token.Plaintext,
 			"userID":          user.ID,
 		}
@@ -75,7 +78,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 			app.logger.PrintError(err, nil)
 		}
 	})
-	err = app.writeJSON(w, http.StatusAccepted, envelope{"user": user}, nil)
+	err = app.writeJSON(w, r, http.StatusAccepted, app.dataEnvelope("user", user, nil), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -94,42 +97,71 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 	v := validator.New()
 	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	user, err := app.models.Users.Activate(input.TokenPlaintext)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			v.AddError("token", "invalid or expired activation token")
-			app.failedValidationResponse(w, r, v.Errors)
+			v.AddErrorCode("token", "invalid", "invalid activation token")
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrTokenExpired):
+			v.AddErrorCode("token", "expired", "activation token has expired")
+			app.failedValidationResponse(w, r, v)
+		case errors.Is(err, data.ErrTokenUsed):
+			v.AddErrorCode("token", "used", "activation token has already been used")
+			app.failedValidationResponse(w, r, v)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	user.Activated = true
-
-	err = app.models.Users.Update(user)
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("user", user, nil), nil)
 	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
-		}
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string
+		Activated *bool
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Email = app.readString(qs, "email", "")
+	input.Activated = app.readBool(qs, "activated", v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", app.config.pagination.defaultPageSize, v)
+	input.Filters.MaxPageSize = app.config.pagination.maxPageSize
+	input.Filters.MaxOffset = app.config.pagination.maxOffset
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+
+	input.Filters.SortSafelist = data.UserSortSafelist
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	users, metadata, err := app.models.Users.GetAll(input.Email, input.Activated, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	metadata.Links = app.paginationLinks(r, metadata)
+
+	err = app.writeJSON(w, r, http.StatusOK, app.dataEnvelope("users", users, metadata), nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"piscine/internal/data"
+	"testing"
+	"time"
+)
+
+func TestActivateUserHandlerTokenReuse(t *testing.T) {
+	app := newTestApplication(t)
+	handler := http.HandlerFunc(app.activateUserHandler)
+
+	// Activating an account uses up all of its activation tokens, so the
+	// expired token belongs to another account.
+	newToken := func(email string, ttl time.Duration) string {
+		user := &data.User{Name: "Test", Email: email}
+		if err := app.models.Users.Insert(user); err != nil {
+			t.Fatal(err)
+		}
+		token, err := app.models.Tokens.New(user.ID, ttl, data.ScopeActivation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token.Plaintext
+	}
+	valid := newToken("alice@example.com", time.Hour)
+	expired := newToken("bob@example.com", -time.Hour)
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"First use", valid, http.StatusOK, ""},
+		{"Reuse", valid, http.StatusUnprocessableEntity, "used"},
+		{"Expired", expired, http.StatusUnprocessableEntity, "expired"},
+	}
+
+	// The cases run in order, as each use of the token changes its state.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(t, handler, http.MethodPut, "/v1/users/activated", `{"token": "`+tt.token+`"}`, nil)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("got status %d; want %d; body: %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+
+			var response struct {
+				Codes map[string]string `json:"codes"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != "" && response.Codes["token"] != "token."+tt.wantCode {
+				t.Errorf("got error codes %v; want token.%s", response.Codes, tt.wantCode)
+			}
+		})
+	}
+}
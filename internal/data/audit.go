@@ -0,0 +1,185 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry records a single write to a footballer. Changes maps each changed
+// column to its old and new values; old is null for creates and new is null
+// for deletes.
+type AuditEntry struct {
+	ID           int64           `json:"id"`
+	Action       string          `json:"action"`
+	FootballerID int64           `json:"footballer_id"`
+	UserID       *int64          `json:"user_id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Changes      json.RawMessage `json:"changes"`
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+// GetForFootballer returns the audit trail of a footballer, oldest first.
+func (m AuditModel) GetForFootballer(footballerID int64) ([]*AuditEntry, error) {
+	query := `
+SELECT id, action, footballer_id, user_id, created_at, changes
+FROM audit_log
+WHERE footballer_id = $1
+ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("AuditModel.GetForFootballer")()
+
+	rows, err := m.DB.QueryContext(ctx, query, footballerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var entry AuditEntry
+
+		err := rows.Scan(&entry.ID, &entry.Action, &entry.FootballerID, &entry.UserID, &entry.CreatedAt, &entry.Changes)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// withAudit runs write in its own transaction together with the audit entry
+// describing it.
+func (m FootballerModel) withAudit(ctx context.Context, action string, id int64, write func(tx *sql.Tx) (int64, error)) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = m.auditedWrite(ctx, tx, action, id, write)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// auditedWrite runs write inside tx and records which columns of footballer id
+// it changed. For creates id is 0 and write returns the new id.
+func (m FootballerModel) auditedWrite(ctx context.Context, tx *sql.Tx, action string, id int64, write func(tx *sql.Tx) (int64, error)) error {
+	var before []byte
+	var err error
+
+	if id != 0 {
+		before, err = snapshotFootballer(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	id, err = write(tx)
+	if err != nil {
+		return err
+	}
+
+	after, err := snapshotFootballer(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	changes, err := diffSnapshots(before, after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO audit_log (action, footballer_id, user_id, changes)
+VALUES ($1, $2, $3, $4)`
+
+	var userID *int64
+	if m.ActorID != 0 {
+		userID = &m.ActorID
+	}
+
+	_, err = tx.ExecContext(ctx, query, action, id, userID, changes)
+	return err
+}
+
+// snapshotFootballer returns the footballer row as JSON, locking it for the
+// rest of the transaction, or nil if the row doesn't exist.
+func snapshotFootballer(ctx context.Context, tx *sql.Tx, id int64) ([]byte, error) {
+	query := `
+SELECT to_jsonb(f)
+FROM footballers f
+WHERE id = $1
+FOR UPDATE`
+
+	var snapshot []byte
+	err := tx.QueryRowContext(ctx, query, id).Scan(&snapshot)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// diffSnapshots returns {"column": {"old": ..., "new": ...}} for every column
+// whose value differs between the two row snapshots.
+func diffSnapshots(before, after []byte) ([]byte, error) {
+	var oldRow, newRow map[string]json.RawMessage
+
+	if before != nil {
+		if err := json.Unmarshal(before, &oldRow); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if err := json.Unmarshal(after, &newRow); err != nil {
+			return nil, err
+		}
+	}
+
+	type change struct {
+		Old json.RawMessage `json:"old"`
+		New json.RawMessage `json:"new"`
+	}
+
+	changes := make(map[string]change)
+	for column, value := range oldRow {
+		if !bytes.Equal(value, newRow[column]) {
+			changes[column] = change{Old: value, New: newRow[column]}
+		}
+	}
+	for column, value := range newRow {
+		if _, ok := oldRow[column]; !ok {
+			changes[column] = change{New: value}
+		}
+	}
+
+	return json.Marshal(changes)
+}
@@ -0,0 +1,278 @@
+package data
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CachedFootballerStore wraps a FootballerStore with a bounded LRU cache for
+// GetAll results. Every write method clears the cache, so a new write method
+// added to FootballerStore must be overridden here as well.
+type CachedFootballerStore struct {
+	FootballerStore
+	cache *listCache
+}
+
+// listCache is shared by a CachedFootballerStore and the copies returned by
+// its WithActor method.
+type listCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	size       int
+	entries    map[string]*list.Element
+	lru        *list.List
+	generation uint64
+
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	key         string
+	footballers []*Footballer
+	metadata    Metadata
+	expires     time.Time
+}
+
+func NewCachedFootballerStore(store FootballerStore, size int, ttl time.Duration) *CachedFootballerStore {
+	return &CachedFootballerStore{
+		FootballerStore: store,
+		cache: &listCache{
+			ttl:     ttl,
+			size:    size,
+			entries: make(map[string]*list.Element),
+			lru:     list.New(),
+		},
+	}
+}
+
+func (c *CachedFootballerStore) WithActor(userID int64) FootballerStore {
+	return &CachedFootballerStore{FootballerStore: c.FootballerStore.WithActor(userID), cache: c.cache}
+}
+
+func (c *CachedFootballerStore) WithContext(ctx context.Context) FootballerStore {
+	return &CachedFootballerStore{FootballerStore: c.FootballerStore.WithContext(ctx), cache: c.cache}
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *CachedFootballerStore) Stats() (hits, misses uint64) {
+	return atomic.LoadUint64(&c.cache.hits), atomic.LoadUint64(&c.cache.misses)
+}
+
+func (c *CachedFootballerStore) GetAll(q FootballerQuery, filters Filters) ([]*Footballer, Metadata, error) {
+	key := cacheKey(q, filters)
+
+	footballers, metadata, generation, ok := c.cache.get(key)
+	if ok {
+		return footballers, metadata, nil
+	}
+
+	footballers, metadata, err := c.FootballerStore.GetAll(q, filters)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	c.cache.put(key, footballers, metadata, generation)
+
+	return footballers, metadata, nil
+}
+
+// get returns a copy of the cached result for key. On a miss it returns the
+// current generation, to be passed to put.
+func (c *listCache) get(key string) ([]*Footballer, Metadata, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(element)
+			atomic.AddUint64(&c.hits, 1)
+			return copyFootballers(entry.footballers), entry.metadata, c.generation, true
+		}
+		c.remove(element)
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+	return nil, Metadata{}, c.generation, false
+}
+
+// put caches a result read at the given generation. A write that finished
+// while the query was running may not be reflected in its result, so it is
+// only cached if nothing has been written since.
+func (c *listCache) put(key string, footballers []*Footballer, metadata Metadata, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	c.add(&cacheEntry{
+		key:         key,
+		footballers: copyFootballers(footballers),
+		metadata:    metadata,
+		expires:     time.Now().Add(c.ttl),
+	})
+}
+
+func (c *listCache) add(entry *cacheEntry) {
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element)
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *listCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// invalidate drops every cached result. Writes call it before returning, so
+// once a client sees its write succeed no cached list predates it.
+func (c *listCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+func (c *CachedFootballerStore) Insert(footballer *Footballer) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Insert(footballer)
+}
+
+func (c *CachedFootballerStore) Upsert(footballer *Footballer) (bool, error) {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Upsert(footballer)
+}
+
+func (c *CachedFootballerStore) Clone(footballer *Footballer) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Clone(footballer)
+}
+
+func (c *CachedFootballerStore) Reset(footballers []*Footballer) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Reset(footballers)
+}
+
+func (c *CachedFootballerStore) Update(footballer *Footballer) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Update(footballer)
+}
+
+func (c *CachedFootballerStore) UpdateRecentGoals(id int64, goals int) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.UpdateRecentGoals(id, goals)
+}
+
+func (c *CachedFootballerStore) SetVerified(id int64, verified bool) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.SetVerified(id, verified)
+}
+
+func (c *CachedFootballerStore) ChangeClub(id int64, club string) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.ChangeClub(id, club)
+}
+
+func (c *CachedFootballerStore) UpdateInjury(id int64, injured bool, returnDate *time.Time) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.UpdateInjury(id, injured, returnDate)
+}
+
+func (c *CachedFootballerStore) IncrementGoals(increments []GoalsIncrement) ([]GoalsTotal, error) {
+	defer c.cache.invalidate()
+	return c.FootballerStore.IncrementGoals(increments)
+}
+
+func (c *CachedFootballerStore) Import(upsert bool, next func() (*Footballer, error), done func(footballer *Footballer, updated bool, err error)) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Import(upsert, next, done)
+}
+
+func (c *CachedFootballerStore) AddPosition(ids []int64, position string) (int64, error) {
+	defer c.cache.invalidate()
+	return c.FootballerStore.AddPosition(ids, position)
+}
+
+func (c *CachedFootballerStore) RecomputeGoals(id int64) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.RecomputeGoals(id)
+}
+
+func (c *CachedFootballerStore) ReindexSearchVectors(batchSize int) (int64, error) {
+	defer c.cache.invalidate()
+	return c.FootballerStore.ReindexSearchVectors(batchSize)
+}
+
+func (c *CachedFootballerStore) Delete(id int64) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Delete(id)
+}
+
+func (c *CachedFootballerStore) Merge(keepID, removeID int64) error {
+	defer c.cache.invalidate()
+	return c.FootballerStore.Merge(keepID, removeID)
+}
+
+// cacheKey normalizes the query so that equivalent requests share an entry.
+func cacheKey(q FootballerQuery, filters Filters) string {
+	position := append([]string(nil), q.Position...)
+	sort.Strings(position)
+
+	boolKey := func(b *bool) string {
+		if b == nil {
+			return ""
+		}
+		return fmt.Sprint(*b)
+	}
+
+	updatedSince := ""
+	if q.UpdatedSince != nil {
+		updatedSince = q.UpdatedSince.UTC().Format(time.RFC3339Nano)
+	}
+
+	return strings.Join([]string{
+		strings.ToLower(strings.TrimSpace(q.Name)),
+		strings.ToLower(strings.TrimSpace(q.Search)),
+		q.Club,
+		strings.Join(position, ","),
+		q.PositionMatch,
+		q.Role,
+		boolKey(q.Verified),
+		boolKey(q.Injured),
+		q.Foot,
+		fmt.Sprint(q.MinHeight),
+		fmt.Sprint(q.MaxHeight),
+		updatedSince,
+		fmt.Sprint(filters.Page),
+		fmt.Sprint(filters.PageSize),
+		filters.Sort,
+	}, "\x00")
+}
+
+// copyFootballers returns shallow copies so callers can modify the returned
+// footballers without changing the cached ones.
+func copyFootballers(footballers []*Footballer) []*Footballer {
+	copies := make([]*Footballer, len(footballers))
+	for i, footballer := range footballers {
+		f := *footballer
+		copies[i] = &f
+	}
+	return copies
+}
@@ -1,18 +1,97 @@
 package data
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"math"
 	"piscine/internal/validator"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Filters struct {
 	Page int
 	PageSize int
+	MaxPageSize int
+	// MaxOffset caps how many records a page may skip, or 0 for no cap.
+	// Deep offsets make PostgreSQL scan and discard every skipped row.
+	MaxOffset int
 	Sort string
 	SortSafelist []string
 }
 
+// SortSafelist returns the accepted sort values for the given columns: each
+// column for ascending order and each column prefixed with "-" for descending.
+func SortSafelist(columns ...string) []string {
+	safelist := make([]string, 0, len(columns)*2)
+	safelist = append(safelist, columns...)
+	for _, column := range columns {
+		safelist = append(safelist, "-"+column)
+	}
+	return safelist
+}
+
+// FootballerSortSafelist lists the sort values accepted by the footballer list.
+var FootballerSortSafelist = SortSafelist("id", "names", "titles", "startedplayyear", "year", "goals")
+
+// UserSortSafelist lists the sort values accepted by the user list.
+var UserSortSafelist = SortSafelist("id", "created_at", "email")
+
+// sortSafelists maps each table to the safelists used to sort it. Add new
+// safelists here so ValidateSortSafelists covers them.
+var sortSafelists = map[string][]string{
+	"footballers": FootballerSortSafelist,
+	"users":       UserSortSafelist,
+}
+
+// ValidateSortSafelists checks that every column in the sort safelists exists
+// in its table, so a misspelt column fails at startup instead of as an SQL
+// error on the first request that sorts by it.
+func ValidateSortSafelists(db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+SELECT column_name FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1`
+
+	problems := []string{}
+	for table, safelist := range sortSafelists {
+		rows, err := db.QueryContext(ctx, query, table)
+		if err != nil {
+			return err
+		}
+
+		columns := make(map[string]bool)
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				rows.Close()
+				return err
+			}
+			columns[column] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, column := range safelist {
+			if !strings.HasPrefix(column, "-") && !columns[column] {
+				problems = append(problems, fmt.Sprintf("%s has no column %q", table, column))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid sort safelist: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 func (f Filters) sortColumn() string {
 	for _, safeValue := range f.SortSafelist {
 		if f.Sort == safeValue {
@@ -36,14 +115,25 @@ func (f Filters) offset() int {
 }
 
 type Metadata struct {
-	CurrentPage int `json:"current_page,omitempty"`
-	PageSize int `json:"page_size,omitempty"`
-	FirstPage int `json:"first_page,omitempty"`
-	LastPage int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	Links        *Links `json:"links,omitempty"`
+}
+
+// Links holds absolute URLs for navigating between pages. Next and Prev are
+// empty on the last and first page.
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
 }
 
-func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+func CalculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
 	}
@@ -56,10 +146,26 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	}
 }
 func ValidateFilters(v *validator.Validator, f Filters) {
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+	v.CheckCode(f.Page > 0, "page", "too_small", "must be greater than zero")
+	v.CheckCode(f.Page <= 10_000_000, "page", "too_large", "must be a maximum of 10 million")
+	v.CheckCode(f.PageSize > 0, "page_size", "too_small", "must be greater than zero")
+	v.CheckCode(f.PageSize <= f.MaxPageSize, "page_size", "too_large", fmt.Sprintf("must be a maximum of %d", f.MaxPageSize))
+	v.CheckCode(f.MaxOffset == 0 || f.offset() <= f.MaxOffset, "page", "too_large", fmt.Sprintf("must not skip more than %d records, narrow the results with filters instead of paging this deep", f.MaxOffset))
 
-	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	ValidateSort(v, f.Sort, f.SortSafelist)
+}
+
+// ValidateSort checks that value is one of the safelisted sort values. Empty
+// and malformed values, such as "-" or "--id", are reported separately from
+// unknown columns.
+func ValidateSort(v *validator.Validator, value string, safelist []string) {
+	column := strings.TrimPrefix(value, "-")
+	switch {
+	case value == "":
+		v.AddErrorCode("sort", "required", "must be provided")
+	case column == "" || strings.HasPrefix(column, "-"):
+		v.AddErrorCode("sort", "invalid_format", "must be a column name, optionally prefixed by one -")
+	case !validator.In(value, safelist...):
+		v.AddErrorCode("sort", "invalid", "invalid sort value")
+	}
 }
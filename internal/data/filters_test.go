@@ -0,0 +1,121 @@
+package data
+
+import (
+	"io/fs"
+	"piscine/internal/validator"
+	"piscine/migrations"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	createTableRE = regexp.MustCompile(`(?is)CREATE TABLE (?:IF NOT EXISTS )?(\w+) \((.*?)\n\s*\);`)
+	addColumnRE   = regexp.MustCompile(`(?i)ALTER TABLE (\w+) ADD COLUMN (?:IF NOT EXISTS )?(\w+)`)
+)
+
+// migratedColumns returns the columns of every table the up migrations
+// create, by table. Unquoted identifiers are lowercased, as PostgreSQL does.
+func migratedColumns(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	files, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := make(map[string]map[string]bool)
+	for _, file := range files {
+		sql, err := fs.ReadFile(migrations.FS, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, match := range createTableRE.FindAllStringSubmatch(string(sql), -1) {
+			columns := make(map[string]bool)
+			for _, line := range strings.Split(match[2], "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 0 {
+					continue
+				}
+				switch name := strings.ToLower(fields[0]); name {
+				case "primary", "unique", "constraint", "foreign", "check":
+				default:
+					columns[name] = true
+				}
+			}
+			tables[strings.ToLower(match[1])] = columns
+		}
+
+		for _, match := range addColumnRE.FindAllStringSubmatch(string(sql), -1) {
+			table := strings.ToLower(match[1])
+			if tables[table] == nil {
+				t.Fatalf("%s adds a column to %s before it is created", file, table)
+			}
+			tables[table][strings.ToLower(match[2])] = true
+		}
+	}
+	return tables
+}
+
+func TestSortSafelistsMatchSchema(t *testing.T) {
+	tables := migratedColumns(t)
+
+	for table, safelist := range sortSafelists {
+		columns, ok := tables[table]
+		if !ok {
+			t.Errorf("no migration creates the %s table", table)
+			continue
+		}
+		for _, value := range safelist {
+			if column := strings.TrimPrefix(value, "-"); !columns[column] {
+				t.Errorf("%s has no column %q for sort value %q", table, column, value)
+			}
+		}
+	}
+}
+
+func TestSortSafelist(t *testing.T) {
+	got := strings.Join(SortSafelist("id", "names"), ",")
+	if want := "id,names,-id,-names"; got != want {
+		t.Errorf("got safelist %s; want %s", got, want)
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"Ascending", "goals", ""},
+		{"Descending", "-goals", ""},
+		{"Empty", "", "must be provided"},
+		{"Only a dash", "-", "must be a column name, optionally prefixed by one -"},
+		{"Two dashes", "--id", "must be a column name, optionally prefixed by one -"},
+		{"Unknown column", "salary", "invalid sort value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateSort(v, tt.value, FootballerSortSafelist)
+
+			if got := v.Errors["sort"]; got != tt.wantErr {
+				t.Errorf("got sort error %q; want %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFiltersSortColumnAndDirection(t *testing.T) {
+	f := Filters{Sort: "-goals", SortSafelist: FootballerSortSafelist}
+	if column, direction := f.sortColumn(), f.sortDirection(); column != "goals" || direction != "DESC" {
+		t.Errorf("got %s %s; want goals DESC", column, direction)
+	}
+
+	f.Sort = "goals"
+	if column, direction := f.sortColumn(), f.sortDirection(); column != "goals" || direction != "ASC" {
+		t.Errorf("got %s %s; want goals ASC", column, direction)
+	}
+}
@@ -3,87 +3,487 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lib/pq"
+	"math"
 	"piscine/internal/validator"
+	"strings"
 	"time"
 )
 
 type Footballer struct {
-	ID              int64     `json:"id"`
-	CreatedAt       time.Time `json:"-"`
-	Name            string    `json:"name"`
-	Titles          int       `json:"titles"`
-	StartedPlayYear int32     `json:"started_play_year,omitempty"`
-	Year            int32     `json:"year,omitempty"`
-	Club            string    `json:"club"`
-	PlayedClubs     int       `json:"played_clubs,omitempty"`
-	Position        []string  `json:"position,omitempty"`
-	Goals           int       `json:"goals,omitempty"`
-	Version         int32     `json:"version"`
+	ID               int64      `json:"id"`
+	CreatedAt        time.Time  `json:"-"`
+	Name             string     `json:"name"`
+	Titles           int        `json:"titles"`
+	StartedPlayYear  int32      `json:"started_play_year,omitempty"`
+	Year             int32      `json:"year,omitempty"`
+	Club             string     `json:"club"`
+	PlayedClubs      int        `json:"played_clubs"`
+	Position         []string   `json:"position,omitempty"`
+	Goals            int        `json:"goals"`
+	RecentGoals      int        `json:"recent_goals"`
+	Verified         bool       `json:"verified"`
+	Injured          bool       `json:"injured"`
+	InjuryReturnDate *time.Time `json:"injury_return_date,omitempty"`
+	PreferredFoot    string     `json:"preferred_foot,omitempty"`
+	HeightCm         *int32     `json:"height_cm,omitempty"`
+	WeightKg         *int32     `json:"weight_kg,omitempty"`
+	HeightIn         *float64   `json:"height_in,omitempty"`
+	WeightLb         *float64   `json:"weight_lb,omitempty"`
+	Roles            []string   `json:"roles,omitempty"`
+	UpdatedAt        time.Time  `json:"-"`
+	Version          int32      `json:"version"`
+
+	// TimeFormat selects how MarshalJSON writes created_at and updated_at:
+	// TimeFormatRFC3339 or TimeFormatUnix. Empty means DefaultTimeFormat.
+	TimeFormat string `json:"-"`
+}
+
+const (
+	TimeFormatRFC3339 = "rfc3339"
+	TimeFormatUnix    = "unix"
+)
+
+var TimeFormats = []string{TimeFormatRFC3339, TimeFormatUnix}
+
+// DefaultTimeFormat is used for footballers without a TimeFormat of their own.
+var DefaultTimeFormat = TimeFormatRFC3339
+
+// formatTime returns t as the configured time format would encode it: a
+// time.Time for RFC3339 or the Unix seconds.
+func (f *Footballer) formatTime(t time.Time) interface{} {
+	format := f.TimeFormat
+	if format == "" {
+		format = DefaultTimeFormat
+	}
+	if format == TimeFormatUnix {
+		return t.Unix()
+	}
+	return t
+}
+
+// OmitZeroYears leaves started_play_year and year out of the JSON output when
+// they are zero. Stats such as goals are always emitted, since 0 is a
+// meaningful value there.
+var OmitZeroYears = true
+
+// MarshalJSON encodes the footballer, writing the timestamps in its
+// TimeFormat and emitting zero years when OmitZeroYears is false.
+func (f Footballer) MarshalJSON() ([]byte, error) {
+	type footballer Footballer
+	createdAt, updatedAt := f.formatTime(f.CreatedAt), f.formatTime(f.UpdatedAt)
+
+	if OmitZeroYears {
+		return json.Marshal(struct {
+			footballer
+			CreatedAt interface{} `json:"created_at"`
+			UpdatedAt interface{} `json:"updated_at"`
+		}{footballer(f), createdAt, updatedAt})
+	}
+
+	return json.Marshal(struct {
+		footballer
+		CreatedAt       interface{} `json:"created_at"`
+		UpdatedAt       interface{} `json:"updated_at"`
+		StartedPlayYear int32       `json:"started_play_year"`
+		Year            int32       `json:"year"`
+	}{footballer(f), createdAt, updatedAt, f.StartedPlayYear, f.Year})
+}
+
+// ToImperial replaces the stored metric height and weight with inches and
+// pounds, rounded to one decimal place. HeightIn and WeightLb are output-only.
+func (f *Footballer) ToImperial() {
+	if f.HeightCm != nil {
+		inches := math.Round(float64(*f.HeightCm)/2.54*10) / 10
+		f.HeightIn, f.HeightCm = &inches, nil
+	}
+	if f.WeightKg != nil {
+		pounds := math.Round(float64(*f.WeightKg)*2.20462*10) / 10
+		f.WeightLb, f.WeightKg = &pounds, nil
+	}
+}
+
+// FootballerFields lists the JSON field names that can be requested through
+// sparse fieldsets.
+var FootballerFields = []string{"id", "name", "titles", "started_play_year", "year", "club", "played_clubs", "position", "goals", "recent_goals", "verified", "injured", "injury_return_date", "preferred_foot", "height_cm", "weight_kg", "height_in", "weight_lb", "roles", "created_at", "updated_at", "version"}
+
+// Select returns a map holding only the requested fields of the footballer,
+// keyed by their JSON names. Unknown field names are ignored.
+func (f *Footballer) Select(fields []string) map[string]interface{} {
+	values := map[string]interface{}{
+		"id":                 f.ID,
+		"name":               f.Name,
+		"titles":             f.Titles,
+		"started_play_year":  f.StartedPlayYear,
+		"year":               f.Year,
+		"club":               f.Club,
+		"played_clubs":       f.PlayedClubs,
+		"position":           f.Position,
+		"goals":              f.Goals,
+		"recent_goals":       f.RecentGoals,
+		"verified":           f.Verified,
+		"injured":            f.Injured,
+		"injury_return_date": f.InjuryReturnDate,
+		"preferred_foot":     f.PreferredFoot,
+		"height_cm":          f.HeightCm,
+		"weight_kg":          f.WeightKg,
+		"height_in":          f.HeightIn,
+		"weight_lb":          f.WeightLb,
+		"roles":              f.Roles,
+		"created_at":         f.formatTime(f.CreatedAt),
+		"updated_at":         f.formatTime(f.UpdatedAt),
+		"version":            f.Version,
+	}
+
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := values[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
+
+func ValidateFields(v *validator.Validator, fields []string) {
+	for _, field := range fields {
+		v.CheckCode(validator.In(field, FootballerFields...), "fields", "invalid", "invalid field "+field)
+	}
+}
+
+// NormalizePositions trims and uppercases position codes in place, so "st",
+// " St" and "ST" are all stored and matched as "ST".
+func NormalizePositions(positions []string) {
+	for i, position := range positions {
+		positions[i] = strings.ToUpper(strings.TrimSpace(position))
+	}
 }
 
+// NormalizeFootballer trims and collapses whitespace in the name and club and
+// uppercases positions. It runs before validation so length checks see the
+// cleaned values.
+func NormalizeFootballer(footballer *Footballer) {
+	footballer.Name = strings.Join(strings.Fields(footballer.Name), " ")
+	footballer.Club = strings.Join(strings.Fields(footballer.Club), " ")
+
+	NormalizePositions(footballer.Position)
+
+	footballer.PreferredFoot = strings.ToLower(strings.TrimSpace(footballer.PreferredFoot))
+}
+
+// PreferredFeet lists the accepted preferred_foot values. An empty value means
+// the preferred foot is unknown.
+var PreferredFeet = []string{"left", "right", "both"}
+
+// Upper bounds for the numeric footballer fields. They catch fat-finger input
+// long before the values could overflow their database columns.
+const (
+	MaxTitles      = 1000
+	MaxGoals       = 2000
+	MaxPlayedClubs = 100
+)
+
+// MinPositions and MaxPositions bound how many positions a footballer lists.
+// They are set from -min-positions and -max-positions.
+var (
+	MinPositions = 1
+	MaxPositions = 6
+)
+
+// Accepted ranges for the optional physical profile, in centimetres and
+// kilograms.
+const (
+	MinHeightCm = 140
+	MaxHeightCm = 220
+	MinWeightKg = 40
+	MaxWeightKg = 150
+)
+
 func ValidateFootballer(v *validator.Validator, footballer *Footballer) {
-	v.Check(footballer.Name != "", "name", "must be provided")
-	v.Check(len(footballer.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.CheckCode(footballer.Name != "", "name", "required", "must be provided")
+	v.CheckCode(len(footballer.Name) <= 500, "name", "too_long", "must not be more than 500 bytes long")
+
+	v.CheckCode(footballer.StartedPlayYear != 0, "started_play_year", "required", "must be provided")
+	v.CheckCode(footballer.StartedPlayYear <= int32(time.Now().Year()), "started_play_year", "in_future", "must not be in the future")
+
+	v.CheckCode(footballer.Year != 0, "Year", "required", "must be provided")
+	v.CheckCode(footballer.Year <= int32(time.Now().Year()), "year", "in_future", "must not be in the future")
+	v.CheckCode(footballer.StartedPlayYear <= footballer.Year, "year", "invalid", "must not be before started_play_year")
+
+	v.CheckCode(footballer.Titles >= 0, "titles", "too_small", "must not be less than zero")
+	v.CheckCode(footballer.Titles <= MaxTitles, "titles", "too_large", fmt.Sprintf("must not be more than %d", MaxTitles))
+
+	v.CheckCode(footballer.PlayedClubs >= 1, "played_clubs", "too_small", "must not be less than 1")
+	v.CheckCode(footballer.PlayedClubs <= MaxPlayedClubs, "played_clubs", "too_large", fmt.Sprintf("must not be more than %d", MaxPlayedClubs))
+
+	v.CheckCode(len(footballer.Club) <= 500, "club", "too_long", "must not be more than 500 bytes long")
 
-	v.Check(footballer.StartedPlayYear != 0, "started_play_year", "must be provided")
-	v.Check(footballer.StartedPlayYear <= int32(time.Now().Year()), "started_play_year", "must not be in the future")
+	v.CheckCode(footballer.Goals >= 0, "goals", "too_small", "must not be negative goals")
+	v.CheckCode(footballer.Goals <= MaxGoals, "goals", "too_large", fmt.Sprintf("must not be more than %d", MaxGoals))
 
-	v.Check(footballer.Year != 0, "Year", "must be provided")
-	v.Check(footballer.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+	v.CheckCode(footballer.Position != nil, "position", "required", "must be provided")
+	v.CheckCode(len(footballer.Position) >= MinPositions, "position", "too_few", fmt.Sprintf("must contain at least %d position(s)", MinPositions))
+	v.CheckCode(len(footballer.Position) <= MaxPositions, "position", "too_many", fmt.Sprintf("must not contain more than %d positions", MaxPositions))
 
-	v.Check(footballer.Titles >= 0, "titles", "must not be less than zero")
+	v.CheckCode(validator.Unique(footballer.Position), "position", "duplicate", "must not contain duplicate values")
 
-	v.Check(footballer.PlayedClubs >= 1, "played_clubs", "must not be less than 1")
+	v.CheckCode(footballer.PreferredFoot == "" || validator.In(footballer.PreferredFoot, PreferredFeet...), "preferred_foot", "invalid", "must be left, right or both")
 
-	v.Check(len(footballer.Club) <= 500, "club", "must not be more than 500 bytes long")
+	if footballer.HeightCm != nil {
+		v.CheckCode(*footballer.HeightCm >= MinHeightCm && *footballer.HeightCm <= MaxHeightCm, "height_cm", "out_of_range", fmt.Sprintf("must be between %d and %d", MinHeightCm, MaxHeightCm))
+	}
+	if footballer.WeightKg != nil {
+		v.CheckCode(*footballer.WeightKg >= MinWeightKg && *footballer.WeightKg <= MaxWeightKg, "weight_kg", "out_of_range", fmt.Sprintf("must be between %d and %d", MinWeightKg, MaxWeightKg))
+	}
+}
+
+// ValidateTitlesPerClub flags footballers with more than maxPerClub titles for
+// every club they played in, which is almost always a data entry error.
+func ValidateTitlesPerClub(v *validator.Validator, footballer *Footballer, maxPerClub int) {
+	v.CheckCode(footballer.Titles <= footballer.PlayedClubs*maxPerClub, "titles", "too_large", fmt.Sprintf("must not be more than %d per played club", maxPerClub))
+}
+
+// ValidatePositionMix flags goalkeepers that also list outfield positions,
+// which is usually a data entry error.
+func ValidatePositionMix(v *validator.Validator, footballer *Footballer) {
+	v.CheckCode(!MixesGoalkeeper(footballer.Position), "position", "invalid", "must not mix GK with outfield positions")
+}
+
+var (
+	ErrNegativeGoals       = errors.New("goals would become negative")
+	ErrDuplicateFootballer = errors.New("duplicate footballer")
+	ErrTooManyPositions    = errors.New("too many positions")
+)
+
+type GoalsIncrement struct {
+	ID         int64 `json:"id"`
+	GoalsDelta int   `json:"goals_delta"`
+}
+
+type GoalsTotal struct {
+	ID      int64 `json:"id"`
+	Goals   int   `json:"goals"`
+	Version int32 `json:"version"`
+}
 
-	v.Check(footballer.Goals >= 0, "goals", "must not be negative goals")
+func ValidateGoalsIncrements(v *validator.Validator, increments []GoalsIncrement) {
+	v.CheckCode(len(increments) >= 1, "footballers", "too_few", "must contain at least 1 entry")
+	v.CheckCode(len(increments) <= 100, "footballers", "too_many", "must not contain more than 100 entries")
 
-	v.Check(footballer.Position != nil, "position", "must be provided")
-	v.Check(len(footballer.Position) >= 1, "position", "must contain at least 1 position in filed")
-	v.Check(len(footballer.Position) <= 6, "position", "must not contain more than  6 positions in filed")
+	seen := make(map[int64]bool)
+	for _, increment := range increments {
+		v.CheckCode(increment.ID > 0, "id", "too_small", "must be a positive integer")
+		v.CheckCode(!seen[increment.ID], "id", "duplicate", "must not contain duplicate values")
+		seen[increment.ID] = true
+	}
+}
 
-	v.Check(validator.Unique(footballer.Position), "position", "must not contain duplicate values")
+// ValidateInjury checks the injury fields. The return date is optional, but
+// when set on an injured footballer it must not be in the past.
+func ValidateInjury(v *validator.Validator, footballer *Footballer) {
+	if footballer.Injured && footballer.InjuryReturnDate != nil {
+		today := time.Now().Truncate(24 * time.Hour)
+		v.CheckCode(!footballer.InjuryReturnDate.Before(today), "injury_return_date", "in_past", "must not be in the past")
+	}
+	v.CheckCode(footballer.Injured || footballer.InjuryReturnDate == nil, "injury_return_date", "invalid", "must not be set unless injured")
 }
 
 type FootballerModel struct {
 	DB *sql.DB
+	// ActorID is the user recorded in the audit log for writes, or 0 if
+	// unknown.
+	ActorID int64
+	// ctx is the parent of every query context, or nil for
+	// context.Background().
+	ctx context.Context
+}
+
+// WithActor returns a copy of the model that attributes its writes to userID
+// in the audit log.
+func (m FootballerModel) WithActor(userID int64) FootballerStore {
+	m.ActorID = userID
+	return m
+}
+
+// WithContext returns a copy of the model whose queries are canceled when ctx
+// is, on top of their own timeouts.
+func (m FootballerModel) WithContext(ctx context.Context) FootballerStore {
+	m.ctx = ctx
+	return m
+}
+
+func (m FootballerModel) context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
 }
 
 func (m FootballerModel) Insert(footballer *Footballer) error {
 	query := `
-INSERT INTO footballers (names, titles,startedplayYear, year,club,playedclubs,positions,goals)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, version`
+INSERT INTO footballers (names, titles,startedplayYear, year,club,playedclubs,positions,goals,preferred_foot,height_cm,weight_kg)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
+RETURNING id, created_at, updated_at, version`
 
-	args := []interface{}{footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year, footballer.Club, footballer.PlayedClubs, pq.Array(footballer.Position), footballer.Goals}
+	args := []interface{}{footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year, footballer.Club, footballer.PlayedClubs, pq.Array(footballer.Position), footballer.Goals, footballer.PreferredFoot, footballer.HeightCm, footballer.WeightKg}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("FootballerModel.Insert")()
 
-	return m.DB.QueryRowContext(ctx,query, args...).Scan(&footballer.ID, &footballer.CreatedAt, &footballer.Version)
+	footballer.Roles = PositionRoles(footballer.Position)
 
+	return m.withAudit(ctx, AuditCreate, 0, func(tx *sql.Tx) (int64, error) {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&footballer.ID, &footballer.CreatedAt, &footballer.UpdatedAt, &footballer.Version)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "footballers_names_club_startedplayyear_key"`:
+				return 0, ErrDuplicateFootballer
+			default:
+				return 0, err
+			}
+		}
+		return footballer.ID, nil
+	})
 }
 
-func (m FootballerModel) Get(id int64) (*Footballer, error) {
-	if id < 1 {
-		return nil, ErrRecordNotFound
-	}
+// Upsert inserts footballer unless one with the same name and club already
+// exists, in which case footballer is replaced by the oldest such record and
+// created is false. Name and club are what the API treats as a duplicate; the
+// database only enforces uniqueness of name, club and started_play_year, so a
+// create racing with this one under a different started_play_year can still
+// add a second record.
+func (m FootballerModel) Upsert(footballer *Footballer) (created bool, err error) {
 	query := `
-SELECT id,created_at,names, titles,startedplayYear, year,club,playedclubs,positions,goals,version
+INSERT INTO footballers (names, titles,startedplayYear, year,club,playedclubs,positions,goals,preferred_foot,height_cm,weight_kg)
+SELECT $1::text, $2::integer, $3::integer, $4::integer, $5::text, $6::integer, $7::text[], $8::integer, NULLIF($9::text, ''), $10::integer, $11::integer
+WHERE NOT EXISTS (SELECT 1 FROM footballers WHERE names = $1 AND club = $5)
+ON CONFLICT DO NOTHING
+RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year, footballer.Club, footballer.PlayedClubs, pq.Array(footballer.Position), footballer.Goals, footballer.PreferredFoot, footballer.HeightCm, footballer.WeightKg}
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Upsert")()
+
+	footballer.Roles = PositionRoles(footballer.Position)
+
+	err = m.withAudit(ctx, AuditCreate, 0, func(tx *sql.Tx) (int64, error) {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&footballer.ID, &footballer.CreatedAt, &footballer.UpdatedAt, &footballer.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrDuplicateFootballer
+		}
+		return footballer.ID, err
+	})
+	if !errors.Is(err, ErrDuplicateFootballer) {
+		return err == nil, err
+	}
+
+	existing, err := m.queryMany("FootballerModel.Upsert", `
+SELECT ` + footballerColumns + `
 FROM footballers
-WHERE id = $1`
+WHERE names = $1 AND club = $2
+ORDER BY id
+LIMIT 1`, footballer.Name, footballer.Club)
+	if err != nil {
+		return false, err
+	}
+	if len(existing) == 0 {
+		return false, ErrRecordNotFound
+	}
 
-	var footballer Footballer
+	*footballer = *existing[0]
+	return false, nil
+}
+
+// Reset deletes every footballer, along with their seasons and audit trail,
+// and inserts the given ones with ids starting from 1 again. It exists for
+// resetting development databases between test runs.
+func (m FootballerModel) Reset(footballers []*Footballer) error {
+	query := `
+INSERT INTO footballers (names, titles,startedplayYear, year,club,playedclubs,positions,goals,preferred_foot,height_cm,weight_kg)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
+RETURNING id, created_at, updated_at, version`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Reset")()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `TRUNCATE footballers, audit_log RESTART IDENTITY CASCADE`)
+	if err != nil {
+		return err
+	}
+
+	for _, footballer := range footballers {
+		args := []interface{}{footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year, footballer.Club, footballer.PlayedClubs, pq.Array(footballer.Position), footballer.Goals, footballer.PreferredFoot, footballer.HeightCm, footballer.WeightKg}
+
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&footballer.ID, &footballer.CreatedAt, &footballer.UpdatedAt, &footballer.Version)
+		if err != nil {
+			return err
+		}
+		footballer.Roles = PositionRoles(footballer.Position)
+	}
+
+	return tx.Commit()
+}
+
+// Clone inserts the footballer as a new record, typically a copy of an
+// existing one with some fields changed. Unlike Insert it also copies the
+// recent goals and injury status. The copy always starts unverified, and gets
+// a new id, version 1 and fresh timestamps.
+func (m FootballerModel) Clone(footballer *Footballer) error {
+	query := `
+INSERT INTO footballers (names, titles,startedplayYear, year,club,playedclubs,positions,goals,recent_goals,injured,injury_return_date,preferred_foot,height_cm,weight_kg)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14)
+RETURNING id, created_at, updated_at, version`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []interface{}{footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year, footballer.Club, footballer.PlayedClubs, pq.Array(footballer.Position), footballer.Goals, footballer.RecentGoals, footballer.Injured, footballer.InjuryReturnDate, footballer.PreferredFoot, footballer.HeightCm, footballer.WeightKg}
 
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("FootballerModel.Clone")()
+
+	footballer.Verified = false
+	footballer.Roles = PositionRoles(footballer.Position)
+
+	return m.withAudit(ctx, AuditCreate, 0, func(tx *sql.Tx) (int64, error) {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&footballer.ID, &footballer.CreatedAt, &footballer.UpdatedAt, &footballer.Version)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "footballers_names_club_startedplayyear_key"`:
+				return 0, ErrDuplicateFootballer
+			default:
+				return 0, err
+			}
+		}
+		return footballer.ID, nil
+	})
+}
+
+// footballerColumns is the column list of every query that returns whole
+// footballers, in the order scanFootballer reads them.
+const footballerColumns = `id,created_at,names, titles,startedplayYear, year,club,playedclubs,positions,goals,recent_goals,verified,injured,injury_return_date,coalesce(preferred_foot, ''),height_cm,weight_kg,updated_at,version`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFootballer reads a row selected with footballerColumns. Columns selected
+// before the list, such as a window count, are scanned into extra first.
+func scanFootballer(scanner rowScanner, extra ...interface{}) (*Footballer, error) {
+	var footballer Footballer
 
-	err := m.DB.QueryRowContext(ctx,query, id).Scan(
+	dest := append(extra,
 		&footballer.ID,
 		&footballer.CreatedAt,
 		&footballer.Name,
@@ -94,8 +494,40 @@ WHERE id = $1`
 		&footballer.PlayedClubs,
 		pq.Array(&footballer.Position),
 		&footballer.Goals,
+		&footballer.RecentGoals,
+		&footballer.Verified,
+		&footballer.Injured,
+		&footballer.InjuryReturnDate,
+		&footballer.PreferredFoot,
+		&footballer.HeightCm,
+		&footballer.WeightKg,
+		&footballer.UpdatedAt,
 		&footballer.Version,
 	)
+	err := scanner.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	footballer.Roles = PositionRoles(footballer.Position)
+	return &footballer, nil
+}
+
+func (m FootballerModel) Get(id int64) (*Footballer, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers
+WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+
+	defer cancel()
+	defer timeQuery("FootballerModel.Get")()
+
+	footballer, err := scanFootballer(m.DB.QueryRowContext(ctx, query, id))
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -104,15 +536,65 @@ WHERE id = $1`
 			return nil, err
 		}
 	}
-	return &footballer, nil
+	return footballer, nil
+}
+
+func (m FootballerModel) Exists(id int64) (bool, error) {
+	if id < 1 {
+		return false, nil
+	}
+	query := `
+SELECT EXISTS(SELECT 1 FROM footballers WHERE id = $1)`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Exists")()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&exists)
+	return exists, err
+}
+
+// Version returns the version of footballer id without fetching the rest of
+// the row, for callers that only need its ETag.
+func (m FootballerModel) Version(id int64) (int32, error) {
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+	query := `
+SELECT version FROM footballers WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Version")()
+
+	var version int32
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrRecordNotFound
+	}
+	return version, err
+}
+
+func (m FootballerModel) ExistsByNameAndClub(name, club string) (bool, error) {
+	query := `
+SELECT EXISTS(SELECT 1 FROM footballers WHERE names = $1 AND club = $2)`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.ExistsByNameAndClub")()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, name, club).Scan(&exists)
+	return exists, err
 }
 
 func (m FootballerModel) Update(footballer *Footballer) error {
 	query := `
 UPDATE footballers 
-SET names = $1, titles = $2, startedplayyear = $3, year = $4, club = $5, playedclubs = $6, positions = $7, goals = $8, version = version + 1
-WHERE id = $9 AND version = $10
-RETURNING version`
+SET names = $1, titles = $2, startedplayyear = $3, year = $4, club = $5, playedclubs = $6, positions = $7, goals = $8, preferred_foot = NULLIF($9, ''), height_cm = $10, weight_kg = $11, updated_at = NOW(), version = version + 1
+WHERE id = $12 AND version = $13
+RETURNING updated_at, version`
 	args := []interface{}{
 		footballer.Name,
 		footballer.Titles,
@@ -122,108 +604,949 @@ RETURNING version`
 		footballer.PlayedClubs,
 		pq.Array(footballer.Position),
 		footballer.Goals,
+		footballer.PreferredFoot,
+		footballer.HeightCm,
+		footballer.WeightKg,
 		footballer.ID,
 		footballer.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("FootballerModel.Update")()
 
-	err := m.DB.QueryRowContext(ctx,query, args...).Scan(&footballer.Version)
+	footballer.Roles = PositionRoles(footballer.Position)
 
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrEditConflict
-		default:
-			return err
+	return m.withAudit(ctx, AuditUpdate, footballer.ID, func(tx *sql.Tx) (int64, error) {
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&footballer.UpdatedAt, &footballer.Version)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return 0, ErrEditConflict
+			case err.Error() == `pq: duplicate key value violates unique constraint "footballers_names_club_startedplayyear_key"`:
+				return 0, ErrDuplicateFootballer
+			default:
+				return 0, err
+			}
 		}
-	}
-
-	return nil
+		return footballer.ID, nil
+	})
 }
 
-func (m FootballerModel) Delete(id int64) error {
+// UpdateRecentGoals sets only the recent_goals value. It deliberately skips the
+// version check, as the stat changes often and is never part of a full update.
+func (m FootballerModel) UpdateRecentGoals(id int64, goals int) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
-DELETE FROM footballers
-WHERE id = $1`
+UPDATE footballers
+SET recent_goals = $1, updated_at = NOW()
+WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.UpdateRecentGoals")()
+
+	return m.withAudit(ctx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, goals, id)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			return 0, ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+// IncrementGoals applies every goals delta in a single transaction. If any
+// footballer is missing or would end up with negative goals, nothing is changed.
+func (m FootballerModel) IncrementGoals(increments []GoalsIncrement) ([]GoalsTotal, error) {
+	query := `
+UPDATE footballers
+SET goals = goals + $1, updated_at = NOW(), version = version + 1
+WHERE id = $2
+RETURNING goals, version`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("FootballerModel.IncrementGoals")()
 
-	result, err := m.DB.ExecContext(ctx,query, id)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
+	totals := make([]GoalsTotal, 0, len(increments))
+	for _, increment := range increments {
+		total := GoalsTotal{ID: increment.ID}
+
+		err := m.auditedWrite(ctx, tx, AuditUpdate, increment.ID, func(tx *sql.Tx) (int64, error) {
+			err := tx.QueryRowContext(ctx, query, increment.GoalsDelta, increment.ID).Scan(&total.Goals, &total.Version)
+			return increment.ID, err
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, fmt.Errorf("footballer %d: %w", increment.ID, ErrRecordNotFound)
+			default:
+				return nil, err
+			}
+		}
+
+		if total.Goals < 0 {
+			return nil, fmt.Errorf("footballer %d: %w", increment.ID, ErrNegativeGoals)
+		}
+
+		totals = append(totals, total)
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	return totals, nil
 }
 
-func (m FootballerModel) GetAll(name string,club string, position []string, filters Filters) ([]*Footballer,Metadata, error) {
-	query := fmt.Sprintf(`
-SELECT count(*) OVER(),id,created_at,names, titles,startedplayYear, year,club,playedclubs,positions,goals,version
+// AddPosition appends position to every listed footballer that doesn't already
+// have it, in a single transaction, and returns how many were changed. If any
+// footballer is missing or would end up with more than MaxPositions positions,
+// nothing is changed.
+func (m FootballerModel) AddPosition(ids []int64, position string) (int64, error) {
+	selectQuery := `
+SELECT positions
 FROM footballers
-WHERE (to_tsvector('simple', names) @@ plainto_tsquery('simple', $1) OR $1 = '')
-AND (positions @> $2 OR $2 = '{}')
-ORDER BY %s %s,id ASC
-LIMIT $3 OFFSET $4`,filters.sortColumn(),filters.sortDirection())
+WHERE id = $1
+FOR UPDATE`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	updateQuery := `
+UPDATE footballers
+SET positions = array_append(positions, $1::text), updated_at = NOW(), version = version + 1
+WHERE id = $2`
 
-	args := []interface{}{name,pq.Array(position),filters.limit(), filters.offset()}
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.AddPosition")()
 
-	rows, err := m.DB.QueryContext(ctx, query,args...)
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, Metadata{},err
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	defer rows.Close()
+	var modified int64
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
 
-	totalRecords := 0
-	footballers := []*Footballer{}
+		var positions []string
+		err := tx.QueryRowContext(ctx, selectQuery, id).Scan(pq.Array(&positions))
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return 0, fmt.Errorf("footballer %d: %w", id, ErrRecordNotFound)
+			default:
+				return 0, err
+			}
+		}
 
-	for rows.Next() {
-		var footballer Footballer
-
-		err := rows.Scan(
-			&totalRecords,
-			&footballer.ID,
-			&footballer.CreatedAt,
-			&footballer.Name,
-			&footballer.Titles,
-			&footballer.StartedPlayYear,
-			&footballer.Year,
-			&footballer.Club,
-			&footballer.PlayedClubs,
-			pq.Array(&footballer.Position),
-			&footballer.Goals,
-			&footballer.Version,
-			)
+		if validator.In(position, positions...) {
+			continue
+		}
+		if len(positions) >= MaxPositions {
+			return 0, fmt.Errorf("footballer %d: %w", id, ErrTooManyPositions)
+		}
+
+		err = m.auditedWrite(ctx, tx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+			_, err := tx.ExecContext(ctx, updateQuery, position, id)
+			return id, err
+		})
 		if err != nil {
-			return nil, Metadata{},err
+			return 0, err
 		}
-		footballers = append(footballers,&footballer)
+		modified++
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, Metadata{}, err
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return modified, nil
+}
+
+// importTimeout bounds a whole Import call, which writes a row per line of an
+// uploaded file in one transaction.
+const importTimeout = time.Minute
 
-	return footballers, metadata, nil
+// Import writes the footballers returned by next in a single transaction,
+// until next returns nil. A footballer whose name and club match an existing
+// one is a duplicate, unless upsert is set, in which case it updates that
+// record (the oldest one, if several match) instead of being inserted. After
+// each footballer, done is called with whether it updated an existing record,
+// or with ErrDuplicateFootballer if it was skipped as a duplicate. Any other
+// error, including one returned by next, rolls the whole import back.
+func (m FootballerModel) Import(upsert bool, next func() (*Footballer, error), done func(footballer *Footballer, updated bool, err error)) error {
+	selectQuery := `
+SELECT id
+FROM footballers
+WHERE names = $1 AND club = $2
+ORDER BY id
+LIMIT 1
+FOR UPDATE`
+
+	insertQuery := `
+INSERT INTO footballers (names, titles,startedplayYear, year,club,playedclubs,positions,goals,preferred_foot,height_cm,weight_kg)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
+RETURNING id, created_at, updated_at, version`
+
+	updateQuery := `
+UPDATE footballers
+SET names = $1, titles = $2, startedplayyear = $3, year = $4, club = $5, playedclubs = $6, positions = $7, goals = $8, preferred_foot = NULLIF($9, ''), height_cm = $10, weight_kg = $11, updated_at = NOW(), version = version + 1
+WHERE id = $12
+RETURNING created_at, updated_at, version`
+
+	ctx, cancel := context.WithTimeout(m.context(), importTimeout)
+	defer cancel()
+	defer timeQuery("FootballerModel.Import")()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for {
+		footballer, err := next()
+		if err != nil {
+			return err
+		}
+		if footballer == nil {
+			break
+		}
+
+		var id int64
+		err = tx.QueryRowContext(ctx, selectQuery, footballer.Name, footballer.Club).Scan(&id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if id != 0 && !upsert {
+			done(footballer, false, ErrDuplicateFootballer)
+			continue
+		}
+
+		args := []interface{}{footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year, footballer.Club, footballer.PlayedClubs, pq.Array(footballer.Position), footballer.Goals, footballer.PreferredFoot, footballer.HeightCm, footballer.WeightKg}
+
+		// The savepoint lets a row that clashes with the unique constraint on
+		// name, club and start year be skipped without aborting the
+		// transaction.
+		_, err = tx.ExecContext(ctx, "SAVEPOINT import_row")
+		if err != nil {
+			return err
+		}
+
+		if id == 0 {
+			err = m.auditedWrite(ctx, tx, AuditCreate, 0, func(tx *sql.Tx) (int64, error) {
+				err := tx.QueryRowContext(ctx, insertQuery, args...).Scan(&footballer.ID, &footballer.CreatedAt, &footballer.UpdatedAt, &footballer.Version)
+				return footballer.ID, err
+			})
+		} else {
+			footballer.ID = id
+			err = m.auditedWrite(ctx, tx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+				err := tx.QueryRowContext(ctx, updateQuery, append(args, id)...).Scan(&footballer.CreatedAt, &footballer.UpdatedAt, &footballer.Version)
+				return id, err
+			})
+		}
+		if err != nil {
+			if err.Error() != `pq: duplicate key value violates unique constraint "footballers_names_club_startedplayyear_key"` {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row")
+			if err != nil {
+				return err
+			}
+			done(footballer, false, ErrDuplicateFootballer)
+			continue
+		}
+
+		footballer.Roles = PositionRoles(footballer.Position)
+		done(footballer, id != 0, nil)
+	}
+
+	return tx.Commit()
 }
 
+// RecomputeGoals sets the career goals total to the sum of the footballer's
+// season_goals rows.
+func (m FootballerModel) RecomputeGoals(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+UPDATE footballers
+SET goals = (SELECT coalesce(sum(goals), 0) FROM season_goals WHERE footballer_id = $1), updated_at = NOW(), version = version + 1
+WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.RecomputeGoals")()
+
+	return m.withAudit(ctx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			return 0, ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+func (m FootballerModel) SetVerified(id int64, verified bool) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+UPDATE footballers
+SET verified = $1, updated_at = NOW(), version = version + 1
+WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.SetVerified")()
+
+	return m.withAudit(ctx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, verified, id)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			return 0, ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+// ChangeClub moves the footballer to club. If it differs from the current club,
+// played_clubs goes up by one in the same statement, up to MaxPlayedClubs.
+func (m FootballerModel) ChangeClub(id int64, club string) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+UPDATE footballers
+SET playedclubs = CASE WHEN club = $1 THEN playedclubs ELSE LEAST(playedclubs + 1, $2) END, club = $1, updated_at = NOW(), version = version + 1
+WHERE id = $3`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.ChangeClub")()
+
+	return m.withAudit(ctx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, club, MaxPlayedClubs, id)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "footballers_names_club_startedplayyear_key"`:
+				return 0, ErrDuplicateFootballer
+			default:
+				return 0, err
+			}
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			return 0, ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+func (m FootballerModel) UpdateInjury(id int64, injured bool, returnDate *time.Time) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+UPDATE footballers
+SET injured = $1, injury_return_date = $2, updated_at = NOW(), version = version + 1
+WHERE id = $3`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.UpdateInjury")()
+
+	return m.withAudit(ctx, AuditUpdate, id, func(tx *sql.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, injured, returnDate, id)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			return 0, ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+func (m FootballerModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+DELETE FROM footballers
+WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Delete")()
+
+	return m.withAudit(ctx, AuditDelete, id, func(tx *sql.Tx) (int64, error) {
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			return 0, ErrRecordNotFound
+		}
+		return id, nil
+	})
+}
+
+// Merge folds the duplicate footballer removeID into keepID in a single
+// transaction. The duplicate's season goals move to keepID, except for seasons
+// keepID already has, and its audit trail is reassigned to keepID before the
+// duplicate is deleted. The kept footballer's own fields are left unchanged.
+func (m FootballerModel) Merge(keepID, removeID int64) error {
+	if keepID < 1 || removeID < 1 || keepID == removeID {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(m.context(), 10*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Merge")()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock both rows in id order, so concurrent merges of the same pair
+	// can't deadlock.
+	rows, err := tx.QueryContext(ctx, `
+SELECT id FROM footballers
+WHERE id IN ($1, $2)
+ORDER BY id
+FOR UPDATE`, keepID, removeID)
+	if err != nil {
+		return err
+	}
+	locked := 0
+	for rows.Next() {
+		locked++
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if locked != 2 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE season_goals
+SET footballer_id = $1
+WHERE footballer_id = $2
+AND season_year NOT IN (SELECT season_year FROM season_goals WHERE footballer_id = $1)`, keepID, removeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE audit_log
+SET footballer_id = $1
+WHERE footballer_id = $2`, keepID, removeID)
+	if err != nil {
+		return err
+	}
+
+	err = m.auditedWrite(ctx, tx, AuditDelete, removeID, func(tx *sql.Tx) (int64, error) {
+		_, err := tx.ExecContext(ctx, `DELETE FROM footballers WHERE id = $1`, removeID)
+		return removeID, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FootballerQuery holds the optional filters shared by FootballerModel.GetAll
+// and FootballerModel.Count.
+const (
+	PositionMatchAll = "all"
+	PositionMatchAny = "any"
+)
+
+type FootballerQuery struct {
+	Name string
+	// Search matches footballers whose name or club contains its words. It
+	// applies on top of Name and Club rather than replacing them.
+	Search   string
+	Club     string
+	Position []string
+	// PositionMatch is PositionMatchAll (the default) to require every
+	// position, or PositionMatchAny to require at least one of them.
+	PositionMatch string
+	Role          string
+	Verified      *bool
+	Injured       *bool
+	Foot          string
+	// MinHeight and MaxHeight bound height_cm; zero means unbounded.
+	MinHeight int32
+	MaxHeight int32
+	// UpdatedSince limits the results to footballers changed after it.
+	UpdatedSince *time.Time
+}
+
+// where returns the WHERE clause for the query, with its args bound from $1.
+func (q FootballerQuery) where() (string, []interface{}) {
+	// @> is containment (all positions), && is overlap (any position).
+	positionOp := "@>"
+	if q.PositionMatch == PositionMatchAny {
+		positionOp = "&&"
+	}
+
+	clause := `
+WHERE (search_vector @@ plainto_tsquery('simple', $1) OR $1 = '')
+AND (club = $2 OR $2 = '')
+AND (positions ` + positionOp + ` $3 OR $3 = '{}')
+AND (positions && $4 OR $4 = '{}')
+AND (verified = $5 OR $5 IS NULL)
+AND (injured = $6 OR $6 IS NULL)
+AND (preferred_foot = $7 OR $7 = '')
+AND (height_cm >= $8 OR $8 = 0)
+AND (height_cm <= $9 OR $9 = 0)
+AND (updated_at > $10 OR $10 IS NULL)
+AND (search_vector @@ plainto_tsquery('simple', $11) OR to_tsvector('simple', club) @@ plainto_tsquery('simple', $11) OR $11 = '')`
+
+	args := []interface{}{q.Name, q.Club, pq.Array(q.Position), pq.Array(RolePositions(q.Role)), q.Verified, q.Injured, q.Foot, q.MinHeight, q.MaxHeight, q.UpdatedSince, q.Search}
+
+	return clause, args
+}
+
+func (m FootballerModel) GetAll(q FootballerQuery, filters Filters) ([]*Footballer,Metadata, error) {
+	where, args := q.where()
+
+	query := fmt.Sprintf(`
+SELECT count(*) OVER(),` + footballerColumns + `
+FROM footballers%s
+ORDER BY %s %s,id ASC
+LIMIT $%d OFFSET $%d`,where,filters.sortColumn(),filters.sortDirection(),len(args)+1,len(args)+2)
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.GetAll")()
+
+	args = append(args, filters.limit(), filters.offset())
+
+	rows, err := m.DB.QueryContext(ctx, query,args...)
+	if err != nil {
+		return nil, Metadata{},err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	footballers := []*Footballer{}
+
+	for rows.Next() {
+		footballer, err := scanFootballer(rows, &totalRecords)
+		if err != nil {
+			return nil, Metadata{},err
+		}
+		footballers = append(footballers,footballer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return footballers, metadata, nil
+}
+
+
+// GetRandom returns a single random footballer matching the optional club and
+// position filters. ORDER BY random() scans and sorts every matching row, which
+// is fine for small tables but grows linearly with the table size. When
+// tablesample is true the query first draws from a ~1% TABLESAMPLE SYSTEM page
+// sample, which is much cheaper on large tables but less uniform, and falls back
+// to the full scan if the sample contains no matching rows.
+func (m FootballerModel) GetRandom(club string, position []string, tablesample bool) (*Footballer, error) {
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers %s
+WHERE (club = $1 OR $1 = '')
+AND (positions @> $2 OR $2 = '{}')
+ORDER BY random()
+LIMIT 1`
+
+	if tablesample {
+		footballer, err := m.getRandom("FootballerModel.GetRandom", fmt.Sprintf(query, "TABLESAMPLE SYSTEM (1)"), club, position)
+		if !errors.Is(err, ErrRecordNotFound) {
+			return footballer, err
+		}
+	}
+
+	return m.getRandom("FootballerModel.GetRandom", fmt.Sprintf(query, ""), club, position)
+}
+
+func (m FootballerModel) getRandom(name, query string, club string, position []string) (*Footballer, error) {
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery(name)()
+
+	footballer, err := scanFootballer(m.DB.QueryRowContext(ctx, query, club, pq.Array(position)))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return footballer, nil
+}
+
+// GetMany returns the footballers with the given ids, ordered by id. Ids that
+// don't exist are silently skipped.
+func (m FootballerModel) GetMany(ids []int64) ([]*Footballer, error) {
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers
+WHERE id = ANY($1)
+ORDER BY id`
+
+	return m.queryMany("FootballerModel.GetMany", query, pq.Array(ids))
+}
+
+// GetByName returns every footballer whose name matches exactly, ignoring
+// case and surrounding whitespace, ordered by id.
+func (m FootballerModel) GetByName(name string) ([]*Footballer, error) {
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers
+WHERE lower(names) = lower($1)
+ORDER BY id`
+
+	return m.queryMany("FootballerModel.GetByName", query, strings.Join(strings.Fields(name), " "))
+}
+
+// streamTimeout bounds a whole Stream call. It is longer than the usual 3s
+// since the caller writes every row to the client as it goes.
+const streamTimeout = 30 * time.Second
+
+// Stream calls fn for every footballer matching the query, in the filters'
+// sort order, without holding the result set in memory. The page and page size
+// are ignored. Streaming stops at the first error fn returns.
+func (m FootballerModel) Stream(q FootballerQuery, filters Filters, fn func(*Footballer) error) error {
+	where, args := q.where()
+
+	query := fmt.Sprintf(`
+SELECT ` + footballerColumns + `
+FROM footballers%s
+ORDER BY %s %s,id ASC`, where, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(m.context(), streamTimeout)
+	defer cancel()
+	defer timeQuery("FootballerModel.Stream")()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		footballer, err := scanFootballer(rows)
+		if err != nil {
+			return err
+		}
+
+		err = fn(footballer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ReindexSearchVectors rebuilds the stored search_vector of up to batchSize
+// footballers whose vector is missing or stale, returning how many were
+// updated. A trigger keeps the vector current on writes, so this is only
+// needed to backfill rows that predate the column.
+func (m FootballerModel) ReindexSearchVectors(batchSize int) (int64, error) {
+	query := `
+UPDATE footballers
+SET search_vector = to_tsvector('simple', names)
+WHERE id IN (
+    SELECT id FROM footballers
+    WHERE search_vector IS DISTINCT FROM to_tsvector('simple', names)
+    LIMIT $1
+)`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.ReindexSearchVectors")()
+
+	result, err := m.DB.ExecContext(ctx, query, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MaxSimilar caps how many similar footballers FindSimilar returns.
+const MaxSimilar = 20
+
+// FindSimilar returns up to limit other footballers sharing at least one
+// position with the given one, best match first. Each shared position scores
+// 100 points, minus the difference in goals and ten points per title of
+// difference.
+func (m FootballerModel) FindSimilar(footballer *Footballer, limit int) ([]*Footballer, error) {
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers
+WHERE id <> $1 AND positions && $2
+ORDER BY cardinality(ARRAY(SELECT unnest(positions) INTERSECT SELECT unnest($2::text[]))) * 100 - abs(goals - $3) - abs(titles - $4) * 10 DESC, id
+LIMIT $5`
+
+	return m.queryMany("FootballerModel.FindSimilar", query, footballer.ID, pq.Array(footballer.Position), footballer.Goals, footballer.Titles, limit)
+}
+
+// Teammates returns the other footballers at the same club as footballer id,
+// by name, matching club names exactly. Footballers without a club have no
+// teammates. Only the current club is compared, as earlier clubs aren't
+// recorded.
+func (m FootballerModel) Teammates(id int64) ([]*Footballer, error) {
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers
+WHERE id <> $1 AND club = (SELECT club FROM footballers WHERE id = $1 AND club <> '')
+ORDER BY names, id`
+
+	return m.queryMany("FootballerModel.Teammates", query, id)
+}
+
+// MaxRecent caps how many footballers Recent returns.
+const MaxRecent = 50
+
+// Recent returns the most recently created footballers, newest first.
+func (m FootballerModel) Recent(limit int) ([]*Footballer, error) {
+	query := `
+SELECT ` + footballerColumns + `
+FROM footballers
+ORDER BY created_at DESC, id DESC
+LIMIT $1`
+
+	return m.queryMany("FootballerModel.Recent", query, limit)
+}
+
+// queryMany runs a query selecting footballerColumns and scans every row.
+func (m FootballerModel) queryMany(name, query string, args ...interface{}) ([]*Footballer, error) {
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery(name)()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	footballers := []*Footballer{}
+
+	for rows.Next() {
+		footballer, err := scanFootballer(rows)
+		if err != nil {
+			return nil, err
+		}
+		footballers = append(footballers, footballer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return footballers, nil
+}
+
+// LastModified returns when the footballers table last changed: the latest
+// updated_at of any footballer, or the latest delete if that is later. It
+// isn't narrowed to a query's filters, since an update that moves a row out of
+// a filtered list changes that list without changing any row still in it. It
+// returns the zero time if there is neither.
+func (m FootballerModel) LastModified() (time.Time, error) {
+	query := `
+SELECT GREATEST(MAX(updated_at), (SELECT MAX(created_at) FROM audit_log WHERE action = 'delete'))
+FROM footballers`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.LastModified")()
+
+	var lastModified sql.NullTime
+	err := m.DB.QueryRowContext(ctx, query).Scan(&lastModified)
+	return lastModified.Time, err
+}
+
+func (m FootballerModel) Count(q FootballerQuery) (int, error) {
+	where, args := q.where()
+
+	query := `
+SELECT count(*)
+FROM footballers` + where
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.Count")()
+
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// ClubStats holds the totals for the footballers listing a club.
+type ClubStats struct {
+	Club         string  `json:"club"`
+	Players      int     `json:"players"`
+	TotalGoals   int     `json:"total_goals"`
+	TotalTitles  int     `json:"total_titles"`
+	AverageGoals float64 `json:"average_goals"`
+}
+
+// ClubStats totals the footballers whose club matches club, ignoring case.
+// Club holds the most common spelling among them. It returns
+// ErrRecordNotFound if no footballer lists the club.
+func (m FootballerModel) ClubStats(club string) (*ClubStats, error) {
+	query := `
+SELECT mode() WITHIN GROUP (ORDER BY club), count(*), coalesce(sum(goals), 0), coalesce(sum(titles), 0), coalesce(avg(goals), 0)
+FROM footballers
+WHERE lower(club) = lower($1) AND club <> ''`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.ClubStats")()
+
+	var stats ClubStats
+	var name sql.NullString
+	err := m.DB.QueryRowContext(ctx, query, club).Scan(&name, &stats.Players, &stats.TotalGoals, &stats.TotalTitles, &stats.AverageGoals)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Players == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	stats.Club = name.String
+	return &stats, nil
+}
+
+// CareerExtremes holds the footballers with the earliest and latest career
+// starts and the longest career. Each is nil when no footballer qualifies.
+type CareerExtremes struct {
+	EarliestStart      *Footballer `json:"earliest_start"`
+	LatestStart        *Footballer `json:"latest_start"`
+	LongestCareer      *Footballer `json:"longest_career"`
+	LongestCareerYears int         `json:"longest_career_years"`
+}
+
+// CareerExtremes finds the footballers with the earliest and latest
+// started_play_year and the largest gap between started_play_year and year,
+// preferring the lowest id on ties. Unknown (zero) years are skipped.
+func (m FootballerModel) CareerExtremes() (*CareerExtremes, error) {
+	query := `
+SELECT
+    (SELECT id FROM footballers WHERE startedplayyear <> 0 ORDER BY startedplayyear, id LIMIT 1),
+    (SELECT id FROM footballers WHERE startedplayyear <> 0 ORDER BY startedplayyear DESC, id LIMIT 1),
+    (SELECT id FROM footballers WHERE startedplayyear <> 0 AND year <> 0 ORDER BY year - startedplayyear DESC, id LIMIT 1)`
+
+	ctx, cancel := context.WithTimeout(m.context(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("FootballerModel.CareerExtremes")()
+
+	var earliest, latest, longest sql.NullInt64
+	err := m.DB.QueryRowContext(ctx, query).Scan(&earliest, &latest, &longest)
+	if err != nil {
+		return nil, err
+	}
+
+	footballers, err := m.GetMany([]int64{earliest.Int64, latest.Int64, longest.Int64})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*Footballer, len(footballers))
+	for _, footballer := range footballers {
+		byID[footballer.ID] = footballer
+	}
+
+	extremes := &CareerExtremes{
+		EarliestStart: byID[earliest.Int64],
+		LatestStart:   byID[latest.Int64],
+		LongestCareer: byID[longest.Int64],
+	}
+	if extremes.LongestCareer != nil {
+		extremes.LongestCareerYears = int(extremes.LongestCareer.Year - extremes.LongestCareer.StartedPlayYear)
+	}
+	return extremes, nil
+}
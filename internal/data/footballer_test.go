@@ -0,0 +1,118 @@
+package data
+
+import (
+	"encoding/json"
+	"piscine/internal/validator"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validFootballer() *Footballer {
+	return &Footballer{
+		Name:            "Lionel Messi",
+		Titles:          40,
+		StartedPlayYear: 2004,
+		Year:            2024,
+		Club:            "Inter Miami",
+		PlayedClubs:     3,
+		Position:        []string{"ST"},
+		Goals:           800,
+	}
+}
+
+func TestValidateFootballerNumericBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		modify  func(*Footballer)
+		wantErr bool
+	}{
+		{"Titles at maximum", "titles", func(f *Footballer) { f.Titles = MaxTitles }, false},
+		{"Titles above maximum", "titles", func(f *Footballer) { f.Titles = MaxTitles + 1 }, true},
+		{"Titles negative", "titles", func(f *Footballer) { f.Titles = -1 }, true},
+		{"Goals at maximum", "goals", func(f *Footballer) { f.Goals = MaxGoals }, false},
+		{"Goals above maximum", "goals", func(f *Footballer) { f.Goals = MaxGoals + 1 }, true},
+		{"Goals zero", "goals", func(f *Footballer) { f.Goals = 0 }, false},
+		{"Played clubs at maximum", "played_clubs", func(f *Footballer) { f.PlayedClubs = MaxPlayedClubs }, false},
+		{"Played clubs above maximum", "played_clubs", func(f *Footballer) { f.PlayedClubs = MaxPlayedClubs + 1 }, true},
+		{"Played clubs zero", "played_clubs", func(f *Footballer) { f.PlayedClubs = 0 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			footballer := validFootballer()
+			tt.modify(footballer)
+
+			v := validator.New()
+			ValidateFootballer(v, footballer)
+
+			_, gotErr := v.Errors[tt.key]
+			if gotErr != tt.wantErr {
+				t.Errorf("got %s error %v (%q); want error %v", tt.key, gotErr, v.Errors[tt.key], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFootballerCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		modify   func(*Footballer)
+		wantCode string
+	}{
+		{"Name missing", "name", func(f *Footballer) { f.Name = "" }, "name.required"},
+		{"Club too long", "club", func(f *Footballer) { f.Club = strings.Repeat("a", 501) }, "club.too_long"},
+		{"Titles negative", "titles", func(f *Footballer) { f.Titles = -1 }, "titles.too_small"},
+		{"Goals above maximum", "goals", func(f *Footballer) { f.Goals = MaxGoals + 1 }, "goals.too_large"},
+		{"Year in the future", "year", func(f *Footballer) { f.Year = int32(time.Now().Year() + 1) }, "year.in_future"},
+		{"Positions duplicated", "position", func(f *Footballer) { f.Position = []string{"ST", "ST"} }, "position.duplicate"},
+		{"Height out of range", "height_cm", func(f *Footballer) { h := int32(MaxHeightCm + 1); f.HeightCm = &h }, "height_cm.out_of_range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			footballer := validFootballer()
+			tt.modify(footballer)
+
+			v := validator.New()
+			ValidateFootballer(v, footballer)
+
+			if got := v.Codes[tt.key]; got != tt.wantCode {
+				t.Errorf("got %s code %q (%q); want %q", tt.key, got, v.Errors[tt.key], tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFootballerMarshalJSONZeroValues(t *testing.T) {
+	defer func() { OmitZeroYears = true }()
+
+	goalkeeper := &Footballer{Name: "Gianluigi Donnarumma", Position: []string{"GK"}}
+
+	for _, omitZeroYears := range []bool{true, false} {
+		OmitZeroYears = omitZeroYears
+
+		js, err := json.Marshal(goalkeeper)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(js, &fields); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, key := range []string{"goals", "recent_goals", "titles", "played_clubs"} {
+			if value, ok := fields[key]; !ok || value != float64(0) {
+				t.Errorf("omitZeroYears=%v: got %s %v (present %v); want 0", omitZeroYears, key, value, ok)
+			}
+		}
+		for _, key := range []string{"started_play_year", "year"} {
+			if _, ok := fields[key]; ok == omitZeroYears {
+				t.Errorf("omitZeroYears=%v: got %s present %v; want %v", omitZeroYears, key, ok, !omitZeroYears)
+			}
+		}
+	}
+}
@@ -0,0 +1,1075 @@
+// Package mock provides in-memory implementations of the data stores so that
+// handlers can be exercised without a PostgreSQL database.
+package mock
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"math"
+	"piscine/internal/data"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewModels returns a data.Models backed by empty in-memory stores.
+func NewModels() data.Models {
+	tokens := &TokenStore{}
+	seasons := &SeasonGoalsStore{}
+	return data.Models{
+		Footballers: &FootballerStore{seasons: seasons},
+		Users:       &UserStore{tokens: tokens},
+		Tokens:      tokens,
+		Permissions: &PermissionStore{},
+		Audit:       &AuditStore{},
+		Seasons:     seasons,
+	}
+}
+
+type SeasonGoalsStore struct {
+	mu      sync.Mutex
+	nextID  int64
+	seasons []*data.SeasonGoals
+}
+
+func (s *SeasonGoalsStore) Insert(season *data.SeasonGoals) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.seasons {
+		if existing.FootballerID == season.FootballerID && existing.SeasonYear == season.SeasonYear {
+			return data.ErrDuplicateSeason
+		}
+	}
+
+	s.nextID++
+	season.ID = s.nextID
+
+	stored := *season
+	s.seasons = append(s.seasons, &stored)
+	return nil
+}
+
+func (s *SeasonGoalsStore) GetAllForFootballer(footballerID int64) ([]*data.SeasonGoals, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seasons := []*data.SeasonGoals{}
+	for _, season := range s.seasons {
+		if season.FootballerID == footballerID {
+			c := *season
+			seasons = append(seasons, &c)
+		}
+	}
+	sort.Slice(seasons, func(i, j int) bool { return seasons[i].SeasonYear < seasons[j].SeasonYear })
+	return seasons, nil
+}
+
+// AuditStore keeps no history; the in-memory footballer store doesn't write
+// audit entries.
+type AuditStore struct{}
+
+func (s *AuditStore) GetForFootballer(footballerID int64) ([]*data.AuditEntry, error) {
+	return []*data.AuditEntry{}, nil
+}
+
+type FootballerStore struct {
+	mu          sync.Mutex
+	nextID      int64
+	footballers []*data.Footballer
+	seasons     *SeasonGoalsStore
+	lastDelete  time.Time
+}
+
+func (s *FootballerStore) Insert(footballer *data.Footballer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.footballers {
+		if existing.Name == footballer.Name && existing.Club == footballer.Club && existing.StartedPlayYear == footballer.StartedPlayYear {
+			return data.ErrDuplicateFootballer
+		}
+	}
+
+	s.nextID++
+	footballer.ID = s.nextID
+	footballer.CreatedAt = time.Now()
+	footballer.UpdatedAt = footballer.CreatedAt
+	footballer.Version = 1
+	footballer.Roles = data.PositionRoles(footballer.Position)
+
+	stored := *footballer
+	s.footballers = append(s.footballers, &stored)
+	return nil
+}
+
+func (s *FootballerStore) Upsert(footballer *data.Footballer) (bool, error) {
+	s.mu.Lock()
+	for _, existing := range s.footballers {
+		if existing.Name == footballer.Name && existing.Club == footballer.Club {
+			*footballer = *existing
+			s.mu.Unlock()
+			return false, nil
+		}
+	}
+	s.mu.Unlock()
+
+	return true, s.Insert(footballer)
+}
+
+func (s *FootballerStore) Clone(footballer *data.Footballer) error {
+	footballer.Verified = false
+	return s.Insert(footballer)
+}
+
+func (s *FootballerStore) Reset(footballers []*data.Footballer) error {
+	s.mu.Lock()
+	s.footballers, s.nextID = nil, 0
+	s.mu.Unlock()
+
+	for _, footballer := range footballers {
+		err := s.Insert(footballer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FootballerStore) WithActor(userID int64) data.FootballerStore {
+	return s
+}
+
+func (s *FootballerStore) WithContext(ctx context.Context) data.FootballerStore {
+	return s
+}
+
+func (s *FootballerStore) Get(id int64) (*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, footballer := range s.footballers {
+		if footballer.ID == id {
+			f := *footballer
+			return &f, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+func (s *FootballerStore) GetMany(ids []int64) ([]*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	footballers := []*data.Footballer{}
+	for _, footballer := range s.footballers {
+		for _, id := range ids {
+			if footballer.ID == id {
+				f := *footballer
+				footballers = append(footballers, &f)
+				break
+			}
+		}
+	}
+	return footballers, nil
+}
+
+func (s *FootballerStore) GetByName(name string) ([]*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = strings.Join(strings.Fields(name), " ")
+
+	footballers := []*data.Footballer{}
+	for _, footballer := range s.footballers {
+		if strings.EqualFold(footballer.Name, name) {
+			f := *footballer
+			footballers = append(footballers, &f)
+		}
+	}
+	return footballers, nil
+}
+
+func (s *FootballerStore) Teammates(id int64) ([]*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	footballer := s.find(id)
+	teammates := []*data.Footballer{}
+	if footballer == nil || footballer.Club == "" {
+		return teammates, nil
+	}
+	for _, candidate := range s.footballers {
+		if candidate.ID != id && candidate.Club == footballer.Club {
+			f := *candidate
+			teammates = append(teammates, &f)
+		}
+	}
+	sort.SliceStable(teammates, func(i, j int) bool { return teammates[i].Name < teammates[j].Name })
+	return teammates, nil
+}
+
+func (s *FootballerStore) Recent(limit int) ([]*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recent := make([]*data.Footballer, 0, len(s.footballers))
+	for _, footballer := range s.footballers {
+		f := *footballer
+		recent = append(recent, &f)
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		if !recent[i].CreatedAt.Equal(recent[j].CreatedAt) {
+			return recent[i].CreatedAt.After(recent[j].CreatedAt)
+		}
+		return recent[i].ID > recent[j].ID
+	})
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent, nil
+}
+
+// FindSimilar uses the same scoring as data.FootballerModel.FindSimilar.
+func (s *FootballerStore) FindSimilar(footballer *data.Footballer, limit int) ([]*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+
+	scores := make(map[int64]int)
+	similar := []*data.Footballer{}
+	for _, candidate := range s.footballers {
+		shared := 0
+		for _, position := range candidate.Position {
+			if contains(footballer.Position, position) {
+				shared++
+			}
+		}
+		if candidate.ID == footballer.ID || shared == 0 {
+			continue
+		}
+
+		scores[candidate.ID] = shared*100 - abs(candidate.Goals-footballer.Goals) - abs(candidate.Titles-footballer.Titles)*10
+		f := *candidate
+		similar = append(similar, &f)
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		return scores[similar[i].ID] > scores[similar[j].ID]
+	})
+
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+func (s *FootballerStore) Exists(id int64) (bool, error) {
+	_, err := s.Get(id)
+	if err == data.ErrRecordNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *FootballerStore) Version(id int64) (int32, error) {
+	footballer, err := s.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return footballer.Version, nil
+}
+
+func (s *FootballerStore) ExistsByNameAndClub(name, club string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, footballer := range s.footballers {
+		if footballer.Name == name && footballer.Club == club {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *FootballerStore) Update(footballer *data.Footballer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.footballers {
+		if existing.ID != footballer.ID && existing.Name == footballer.Name && existing.Club == footballer.Club && existing.StartedPlayYear == footballer.StartedPlayYear {
+			return data.ErrDuplicateFootballer
+		}
+	}
+
+	for i, stored := range s.footballers {
+		if stored.ID == footballer.ID {
+			if stored.Version != footballer.Version {
+				return data.ErrEditConflict
+			}
+			footballer.Version++
+			footballer.UpdatedAt = time.Now()
+			footballer.Roles = data.PositionRoles(footballer.Position)
+			f := *footballer
+			s.footballers[i] = &f
+			return nil
+		}
+	}
+	return data.ErrEditConflict
+}
+
+func (s *FootballerStore) UpdateRecentGoals(id int64, goals int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, footballer := range s.footballers {
+		if footballer.ID == id {
+			footballer.RecentGoals = goals
+			footballer.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return data.ErrRecordNotFound
+}
+
+func (s *FootballerStore) IncrementGoals(increments []data.GoalsIncrement) ([]data.GoalsTotal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := make(map[int64]data.Footballer)
+	totals := make([]data.GoalsTotal, 0, len(increments))
+	for _, increment := range increments {
+		footballer, ok := updated[increment.ID]
+		if !ok {
+			stored := s.find(increment.ID)
+			if stored == nil {
+				return nil, fmt.Errorf("footballer %d: %w", increment.ID, data.ErrRecordNotFound)
+			}
+			footballer = *stored
+		}
+
+		footballer.Goals += increment.GoalsDelta
+		footballer.Version++
+		footballer.UpdatedAt = time.Now()
+		if footballer.Goals < 0 {
+			return nil, fmt.Errorf("footballer %d: %w", increment.ID, data.ErrNegativeGoals)
+		}
+
+		updated[increment.ID] = footballer
+		totals = append(totals, data.GoalsTotal{ID: footballer.ID, Goals: footballer.Goals, Version: footballer.Version})
+	}
+
+	for id, footballer := range updated {
+		*s.find(id) = footballer
+	}
+
+	return totals, nil
+}
+
+func (s *FootballerStore) AddPosition(ids []int64, position string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var targets []*data.Footballer
+	for _, id := range ids {
+		footballer := s.find(id)
+		if footballer == nil {
+			return 0, fmt.Errorf("footballer %d: %w", id, data.ErrRecordNotFound)
+		}
+		if contains(footballer.Position, position) || containsFootballer(targets, footballer) {
+			continue
+		}
+		if len(footballer.Position) >= data.MaxPositions {
+			return 0, fmt.Errorf("footballer %d: %w", id, data.ErrTooManyPositions)
+		}
+		targets = append(targets, footballer)
+	}
+
+	for _, footballer := range targets {
+		footballer.Position = append(append([]string(nil), footballer.Position...), position)
+		footballer.Roles = data.PositionRoles(footballer.Position)
+		footballer.Version++
+		footballer.UpdatedAt = time.Now()
+	}
+	return int64(len(targets)), nil
+}
+
+func containsFootballer(footballers []*data.Footballer, footballer *data.Footballer) bool {
+	for _, f := range footballers {
+		if f == footballer {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FootballerStore) find(id int64) *data.Footballer {
+	for _, footballer := range s.footballers {
+		if footballer.ID == id {
+			return footballer
+		}
+	}
+	return nil
+}
+
+func (s *FootballerStore) SetVerified(id int64, verified bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	footballer := s.find(id)
+	if footballer == nil {
+		return data.ErrRecordNotFound
+	}
+	footballer.Verified = verified
+	footballer.Version++
+	footballer.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *FootballerStore) ChangeClub(id int64, club string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	footballer := s.find(id)
+	if footballer == nil {
+		return data.ErrRecordNotFound
+	}
+	if footballer.Club == club {
+		footballer.Version++
+		footballer.UpdatedAt = time.Now()
+		return nil
+	}
+	for _, existing := range s.footballers {
+		if existing.ID != id && existing.Name == footballer.Name && existing.Club == club && existing.StartedPlayYear == footballer.StartedPlayYear {
+			return data.ErrDuplicateFootballer
+		}
+	}
+	footballer.Club = club
+	if footballer.PlayedClubs < data.MaxPlayedClubs {
+		footballer.PlayedClubs++
+	}
+	footballer.Version++
+	footballer.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *FootballerStore) Import(upsert bool, next func() (*data.Footballer, error), done func(footballer *data.Footballer, updated bool, err error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Work on copies so that an error leaves the store untouched, like a
+	// rolled back transaction.
+	footballers := append([]*data.Footballer(nil), s.footballers...)
+	nextID := s.nextID
+
+	for {
+		footballer, err := next()
+		if err != nil {
+			return err
+		}
+		if footballer == nil {
+			break
+		}
+
+		match := -1
+		duplicate := false
+		for i, existing := range footballers {
+			if existing.Name != footballer.Name || existing.Club != footballer.Club {
+				continue
+			}
+			if match == -1 {
+				match = i
+			} else if existing.StartedPlayYear == footballer.StartedPlayYear {
+				duplicate = true
+			}
+		}
+		if duplicate || (match != -1 && !upsert) {
+			done(footballer, false, data.ErrDuplicateFootballer)
+			continue
+		}
+
+		footballer.Roles = data.PositionRoles(footballer.Position)
+		footballer.UpdatedAt = time.Now()
+
+		if match == -1 {
+			nextID++
+			footballer.ID = nextID
+			footballer.CreatedAt = footballer.UpdatedAt
+			footballer.Version = 1
+			f := *footballer
+			footballers = append(footballers, &f)
+			done(footballer, false, nil)
+			continue
+		}
+
+		f := *footballers[match]
+		f.Name, f.Titles, f.StartedPlayYear, f.Year = footballer.Name, footballer.Titles, footballer.StartedPlayYear, footballer.Year
+		f.Club, f.PlayedClubs, f.Position, f.Goals = footballer.Club, footballer.PlayedClubs, footballer.Position, footballer.Goals
+		f.PreferredFoot, f.HeightCm, f.WeightKg = footballer.PreferredFoot, footballer.HeightCm, footballer.WeightKg
+		f.Roles, f.UpdatedAt = footballer.Roles, footballer.UpdatedAt
+		f.Version++
+		footballers[match] = &f
+
+		footballer.ID, footballer.CreatedAt, footballer.Version = f.ID, f.CreatedAt, f.Version
+		done(footballer, true, nil)
+	}
+
+	s.footballers, s.nextID = footballers, nextID
+	return nil
+}
+
+func (s *FootballerStore) RecomputeGoals(id int64) error {
+	seasons, err := s.seasons.GetAllForFootballer(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	footballer := s.find(id)
+	if footballer == nil {
+		return data.ErrRecordNotFound
+	}
+
+	footballer.Goals = 0
+	for _, season := range seasons {
+		footballer.Goals += season.Goals
+	}
+	footballer.Version++
+	footballer.UpdatedAt = time.Now()
+	return nil
+}
+
+// ReindexSearchVectors is a no-op, as the mock matches names directly.
+func (s *FootballerStore) ReindexSearchVectors(batchSize int) (int64, error) {
+	return 0, nil
+}
+
+func (s *FootballerStore) UpdateInjury(id int64, injured bool, returnDate *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	footballer := s.find(id)
+	if footballer == nil {
+		return data.ErrRecordNotFound
+	}
+	footballer.Injured = injured
+	footballer.InjuryReturnDate = returnDate
+	footballer.Version++
+	footballer.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *FootballerStore) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, footballer := range s.footballers {
+		if footballer.ID == id {
+			s.footballers = append(s.footballers[:i], s.footballers[i+1:]...)
+			s.lastDelete = time.Now()
+			return nil
+		}
+	}
+	return data.ErrRecordNotFound
+}
+
+// Merge deletes removeID once both footballers are found. Seasons live in a
+// separate store here, so they are not moved.
+func (s *FootballerStore) Merge(keepID, removeID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keepID == removeID || s.find(keepID) == nil || s.find(removeID) == nil {
+		return data.ErrRecordNotFound
+	}
+
+	for i, footballer := range s.footballers {
+		if footballer.ID == removeID {
+			s.footballers = append(s.footballers[:i], s.footballers[i+1:]...)
+			break
+		}
+	}
+	s.lastDelete = time.Now()
+	return nil
+}
+
+// GetAll applies the query filters and pagination. Results are always ordered
+// by id; the name search and sort are ignored.
+func (s *FootballerStore) GetAll(q data.FootballerQuery, filters data.Filters) ([]*data.Footballer, data.Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matched := s.query(q)
+
+	start, end, metadata := paginate(len(matched), filters)
+	return matched[start:end], metadata, nil
+}
+
+// Stream calls fn for every match in id order; the sort is ignored.
+func (s *FootballerStore) Stream(q data.FootballerQuery, filters data.Filters, fn func(*data.Footballer) error) error {
+	s.mu.Lock()
+	matched := s.query(q)
+	s.mu.Unlock()
+
+	for _, footballer := range matched {
+		err := fn(footballer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FootballerStore) Count(q data.FootballerQuery) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.query(q)), nil
+}
+
+func (s *FootballerStore) ClubStats(club string) (*data.ClubStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &data.ClubStats{}
+	spellings := make(map[string]int)
+	for _, footballer := range s.footballers {
+		if footballer.Club == "" || !strings.EqualFold(footballer.Club, club) {
+			continue
+		}
+		stats.Players++
+		stats.TotalGoals += footballer.Goals
+		stats.TotalTitles += footballer.Titles
+		spellings[footballer.Club]++
+	}
+	if stats.Players == 0 {
+		return nil, data.ErrRecordNotFound
+	}
+
+	for spelling, n := range spellings {
+		if n > spellings[stats.Club] || (n == spellings[stats.Club] && spelling < stats.Club) {
+			stats.Club = spelling
+		}
+	}
+	stats.AverageGoals = float64(stats.TotalGoals) / float64(stats.Players)
+	return stats, nil
+}
+
+// CareerExtremes follows data.FootballerModel.CareerExtremes; footballers are
+// kept in id order, so strict comparisons keep the lowest id on ties.
+func (s *FootballerStore) CareerExtremes() (*data.CareerExtremes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	extremes := &data.CareerExtremes{}
+	for _, footballer := range s.footballers {
+		if footballer.StartedPlayYear == 0 {
+			continue
+		}
+		f := *footballer
+		if extremes.EarliestStart == nil || f.StartedPlayYear < extremes.EarliestStart.StartedPlayYear {
+			extremes.EarliestStart = &f
+		}
+		if extremes.LatestStart == nil || f.StartedPlayYear > extremes.LatestStart.StartedPlayYear {
+			extremes.LatestStart = &f
+		}
+		if f.Year == 0 {
+			continue
+		}
+		if years := int(f.Year - f.StartedPlayYear); extremes.LongestCareer == nil || years > extremes.LongestCareerYears {
+			extremes.LongestCareer = &f
+			extremes.LongestCareerYears = years
+		}
+	}
+	return extremes, nil
+}
+
+func (s *FootballerStore) LastModified() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastModified := s.lastDelete
+	for _, footballer := range s.footballers {
+		if footballer.UpdatedAt.After(lastModified) {
+			lastModified = footballer.UpdatedAt
+		}
+	}
+	return lastModified, nil
+}
+
+// query applies every FootballerQuery filter except the full-text name and q
+// searches.
+func (s *FootballerStore) query(q data.FootballerQuery) []*data.Footballer {
+	matched := []*data.Footballer{}
+	for _, footballer := range s.footballers {
+		if !s.matches(footballer, q.Club, q.Position, q.PositionMatch == data.PositionMatchAny) {
+			continue
+		}
+		if q.Role != "" && !contains(footballer.Roles, q.Role) {
+			continue
+		}
+		if q.Verified != nil && footballer.Verified != *q.Verified {
+			continue
+		}
+		if q.Injured != nil && footballer.Injured != *q.Injured {
+			continue
+		}
+		if q.Foot != "" && footballer.PreferredFoot != q.Foot {
+			continue
+		}
+		if (q.MinHeight != 0 || q.MaxHeight != 0) && footballer.HeightCm == nil {
+			continue
+		}
+		if q.MinHeight != 0 && *footballer.HeightCm < q.MinHeight {
+			continue
+		}
+		if q.MaxHeight != 0 && *footballer.HeightCm > q.MaxHeight {
+			continue
+		}
+		if q.UpdatedSince != nil && !footballer.UpdatedAt.After(*q.UpdatedSince) {
+			continue
+		}
+		f := *footballer
+		matched = append(matched, &f)
+	}
+	return matched
+}
+
+func (s *FootballerStore) GetRandom(club string, position []string, tablesample bool) (*data.Footballer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, footballer := range s.footballers {
+		if s.matches(footballer, club, position, false) {
+			f := *footballer
+			return &f, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+func (s *FootballerStore) matches(footballer *data.Footballer, club string, position []string, any bool) bool {
+	if club != "" && footballer.Club != club {
+		return false
+	}
+	if any && len(position) > 0 {
+		for _, p := range position {
+			if contains(footballer.Position, p) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range position {
+		if !contains(footballer.Position, p) {
+			return false
+		}
+	}
+	return true
+}
+
+type UserStore struct {
+	mu     sync.Mutex
+	nextID int64
+	users  []*data.User
+	tokens *TokenStore
+}
+
+func (s *UserStore) Insert(user *data.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.users {
+		if stored.Email == user.Email {
+			return data.ErrDuplicateEmail
+		}
+	}
+
+	s.nextID++
+	user.ID = s.nextID
+	user.CreatedAt = time.Now()
+	user.Version = 1
+
+	u := *user
+	s.users = append(s.users, &u)
+	return nil
+}
+
+func (s *UserStore) GetByEmail(email string) (*data.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+func (s *UserStore) Update(user *data.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, stored := range s.users {
+		if stored.ID == user.ID {
+			if stored.Version != user.Version {
+				return data.ErrEditConflict
+			}
+			user.Version++
+			u := *user
+			s.users[i] = &u
+			return nil
+		}
+	}
+	return data.ErrEditConflict
+}
+
+func (s *UserStore) GetForToken(tokenScope, tokenPlaintext string) (*data.User, error) {
+	userID, err := s.tokens.lookup(tokenScope, tokenPlaintext, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.ID == userID {
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+func (s *UserStore) Activate(tokenPlaintext string) (*data.User, error) {
+	userID, err := s.tokens.lookup(data.ScopeActivation, tokenPlaintext, true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.ID == userID {
+			user.Activated = true
+			user.Version++
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+// GetAll filters users by email substring and activation status, ordered by id.
+func (s *UserStore) GetAll(email string, activated *bool, filters data.Filters) ([]*data.User, data.Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matched := []*data.User{}
+	for _, user := range s.users {
+		if !strings.Contains(strings.ToLower(user.Email), strings.ToLower(email)) {
+			continue
+		}
+		if activated != nil && user.Activated != *activated {
+			continue
+		}
+		u := *user
+		matched = append(matched, &u)
+	}
+
+	start, end, metadata := paginate(len(matched), filters)
+	return matched[start:end], metadata, nil
+}
+
+type TokenStore struct {
+	mu     sync.Mutex
+	tokens []*data.Token
+}
+
+func (s *TokenStore) New(userID int64, ttl time.Duration, scope string) (*data.Token, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &data.Token{
+		Plaintext: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes),
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+	}
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	err = s.Insert(token)
+	return token, err
+}
+
+func (s *TokenStore) Insert(token *data.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := *token
+	s.tokens = append(s.tokens, &t)
+	return nil
+}
+
+func (s *TokenStore) Peek(scope string, hash []byte) (*data.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.Scope != scope || string(token.Hash) != string(hash) {
+			continue
+		}
+		switch {
+		case token.UsedAt != nil:
+			return nil, data.ErrTokenUsed
+		case !token.Expiry.After(time.Now()):
+			return nil, data.ErrTokenExpired
+		}
+		t := *token
+		t.Plaintext = ""
+		return &t, nil
+	}
+	return nil, data.ErrRecordNotFound
+}
+
+func (s *TokenStore) DeleteAllForUser(scope string, userID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.tokens[:0]
+	for _, token := range s.tokens {
+		if token.Scope != scope || token.UserID != userID {
+			kept = append(kept, token)
+		}
+	}
+	deleted := int64(len(s.tokens) - len(kept))
+	s.tokens = kept
+	return deleted, nil
+}
+
+func (s *TokenStore) DeleteExpired() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	kept := s.tokens[:0]
+	for _, token := range s.tokens {
+		if token.Expiry.Before(time.Now()) {
+			deleted++
+			continue
+		}
+		kept = append(kept, token)
+	}
+	s.tokens = kept
+	return deleted, nil
+}
+
+// lookup returns the owner of a token that can still be redeemed. With use
+// set, it marks every token of that owner and scope as used.
+func (s *TokenStore) lookup(scope, plaintext string, use bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(plaintext))
+	for _, token := range s.tokens {
+		if token.Scope != scope || string(token.Hash) != string(hash[:]) {
+			continue
+		}
+		switch {
+		case token.UsedAt != nil:
+			return 0, data.ErrTokenUsed
+		case !token.Expiry.After(time.Now()):
+			return 0, data.ErrTokenExpired
+		}
+
+		if use {
+			now := time.Now()
+			for _, t := range s.tokens {
+				if t.Scope == scope && t.UserID == token.UserID && t.UsedAt == nil {
+					t.UsedAt = &now
+				}
+			}
+		}
+		return token.UserID, nil
+	}
+	return 0, data.ErrRecordNotFound
+}
+
+type PermissionStore struct {
+	mu          sync.Mutex
+	permissions map[int64]data.Permissions
+}
+
+func (s *PermissionStore) GetAllForUser(userID int64) (data.Permissions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append(data.Permissions(nil), s.permissions[userID]...), nil
+}
+
+func (s *PermissionStore) AddForUser(userID int64, codes ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.permissions == nil {
+		s.permissions = make(map[int64]data.Permissions)
+	}
+	for _, code := range codes {
+		if !s.permissions[userID].Include(code) {
+			s.permissions[userID] = append(s.permissions[userID], code)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func paginate(total int, filters data.Filters) (int, int, data.Metadata) {
+	start := (filters.Page - 1) * filters.PageSize
+	if start > total {
+		start = total
+	}
+	end := start + filters.PageSize
+	if end > total {
+		end = total
+	}
+
+	if total == 0 {
+		return start, end, data.Metadata{}
+	}
+
+	return start, end, data.Metadata{
+		CurrentPage:  filters.Page,
+		PageSize:     filters.PageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(total) / float64(filters.PageSize))),
+		TotalRecords: total,
+	}
+}
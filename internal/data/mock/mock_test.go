@@ -0,0 +1,39 @@
+package mock
+
+import (
+	"piscine/internal/data"
+	"testing"
+	"time"
+)
+
+func TestLastModifiedSeesRowsLeavingAFilter(t *testing.T) {
+	s := &FootballerStore{}
+
+	for _, name := range []string{"Lionel Messi", "Luis Suarez"} {
+		err := s.Insert(&data.Footballer{Name: name, Club: "Inter Miami", Position: []string{"forward"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Back-date both rows so the move below is clearly the latest change.
+	hourAgo := time.Now().Add(-time.Hour)
+	for _, footballer := range s.footballers {
+		footballer.UpdatedAt = hourAgo
+	}
+
+	err := s.ChangeClub(1, "Barcelona")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Inter Miami list lost Messi, though Suarez, the only row still in
+	// it, hasn't changed.
+	lastModified, err := s.LastModified()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lastModified.After(hourAgo) {
+		t.Errorf("got last modified %v; want the time Messi changed club", lastModified)
+	}
+}
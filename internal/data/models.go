@@ -1,8 +1,11 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"github.com/lib/pq"
+	"time"
 )
 
 var (
@@ -10,11 +13,117 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// IsTimeout reports whether err comes from a query that ran past its context
+// deadline. Depending on when the deadline hits, database/sql returns the
+// context error or PostgreSQL reports the statement as canceled.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// SlowQueryThreshold is how long a model method may spend on the database
+// before it is reported to SlowQueryLogger; 0 disables the report. It is set
+// from -db-slow-query-threshold.
+var SlowQueryThreshold time.Duration
+
+// SlowQueryLogger is called with the name of every model method that ran
+// longer than SlowQueryThreshold and how long it took. The query arguments
+// are deliberately not passed on, as they can hold personal data and secrets.
+var SlowQueryLogger func(name string, duration time.Duration)
+
+// timeQuery starts timing the model method name. Call it as
+// defer timeQuery("UserModel.GetByEmail")().
+func timeQuery(name string) func() {
+	start := time.Now()
+	return func() {
+		duration := time.Since(start)
+		if SlowQueryThreshold > 0 && duration > SlowQueryThreshold && SlowQueryLogger != nil {
+			SlowQueryLogger(name, duration)
+		}
+	}
+}
+
+// FootballerStore is implemented by FootballerModel and by the in-memory
+// store in the mock package.
+type FootballerStore interface {
+	Insert(footballer *Footballer) error
+	Upsert(footballer *Footballer) (created bool, err error)
+	Clone(footballer *Footballer) error
+	Reset(footballers []*Footballer) error
+	Get(id int64) (*Footballer, error)
+	GetMany(ids []int64) ([]*Footballer, error)
+	GetByName(name string) ([]*Footballer, error)
+	FindSimilar(footballer *Footballer, limit int) ([]*Footballer, error)
+	Teammates(id int64) ([]*Footballer, error)
+	Recent(limit int) ([]*Footballer, error)
+	Exists(id int64) (bool, error)
+	Version(id int64) (int32, error)
+	ExistsByNameAndClub(name, club string) (bool, error)
+	Update(footballer *Footballer) error
+	UpdateRecentGoals(id int64, goals int) error
+	SetVerified(id int64, verified bool) error
+	ChangeClub(id int64, club string) error
+	UpdateInjury(id int64, injured bool, returnDate *time.Time) error
+	IncrementGoals(increments []GoalsIncrement) ([]GoalsTotal, error)
+	AddPosition(ids []int64, position string) (int64, error)
+	Import(upsert bool, next func() (*Footballer, error), done func(footballer *Footballer, updated bool, err error)) error
+	RecomputeGoals(id int64) error
+	ReindexSearchVectors(batchSize int) (int64, error)
+	Delete(id int64) error
+	Merge(keepID, removeID int64) error
+	GetAll(q FootballerQuery, filters Filters) ([]*Footballer, Metadata, error)
+	Stream(q FootballerQuery, filters Filters, fn func(*Footballer) error) error
+	Count(q FootballerQuery) (int, error)
+	ClubStats(club string) (*ClubStats, error)
+	CareerExtremes() (*CareerExtremes, error)
+	LastModified() (time.Time, error)
+	GetRandom(club string, position []string, tablesample bool) (*Footballer, error)
+	WithActor(userID int64) FootballerStore
+	WithContext(ctx context.Context) FootballerStore
+}
+
+type SeasonGoalsStore interface {
+	Insert(season *SeasonGoals) error
+	GetAllForFootballer(footballerID int64) ([]*SeasonGoals, error)
+}
+
+type AuditStore interface {
+	GetForFootballer(footballerID int64) ([]*AuditEntry, error)
+}
+
+type UserStore interface {
+	Insert(user *User) error
+	GetByEmail(email string) (*User, error)
+	Update(user *User) error
+	GetForToken(tokenScope, tokenPlaintext string) (*User, error)
+	Activate(tokenPlaintext string) (*User, error)
+	GetAll(email string, activated *bool, filters Filters) ([]*User, Metadata, error)
+}
+
+type TokenStore interface {
+	New(userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(token *Token) error
+	Peek(scope string, hash []byte) (*Token, error)
+	DeleteAllForUser(scope string, userID int64) (int64, error)
+	DeleteExpired() (int64, error)
+}
+
+type PermissionStore interface {
+	GetAllForUser(userID int64) (Permissions, error)
+	AddForUser(userID int64, codes ...string) error
+}
+
 type Models struct {
-	Footballers FootballerModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
+	Footballers FootballerStore
+	Users       UserStore
+	Tokens      TokenStore
+	Permissions PermissionStore
+	Audit       AuditStore
+	Seasons     SeasonGoalsStore
 }
 
 func NewModels(db *sql.DB) Models {
@@ -23,5 +132,7 @@ func NewModels(db *sql.DB) Models {
 		Permissions: PermissionModel{DB: db},
 		Tokens:      TokenModel{DB: db},
 		Users:       UserModel{DB: db},
+		Audit:       AuditModel{DB: db},
+		Seasons:     SeasonGoalsModel{DB: db},
 	}
 }
@@ -31,6 +31,7 @@ INNER JOIN users ON users_permissions.user_id = users.id
 WHERE users.id = $1`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("PermissionModel.GetAllForUser")()
 	rows, err := m.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
@@ -57,6 +58,7 @@ INSERT INTO users_permissions
 SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("PermissionModel.AddForUser")()
 	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
 	return err
 }
@@ -0,0 +1,86 @@
+package data
+
+import "sort"
+
+const (
+	RoleGoalkeeper = "GK"
+	RoleDefender   = "DEF"
+	RoleMidfielder = "MID"
+	RoleForward    = "FWD"
+)
+
+var Roles = []string{RoleGoalkeeper, RoleDefender, RoleMidfielder, RoleForward}
+
+var positionRoles = map[string]string{
+	"GK":  RoleGoalkeeper,
+	"CB":  RoleDefender,
+	"LB":  RoleDefender,
+	"RB":  RoleDefender,
+	"LWB": RoleDefender,
+	"RWB": RoleDefender,
+	"SW":  RoleDefender,
+	"DF":  RoleDefender,
+	"CDM": RoleMidfielder,
+	"DM":  RoleMidfielder,
+	"CM":  RoleMidfielder,
+	"CAM": RoleMidfielder,
+	"AM":  RoleMidfielder,
+	"LM":  RoleMidfielder,
+	"RM":  RoleMidfielder,
+	"MF":  RoleMidfielder,
+	"ST":  RoleForward,
+	"CF":  RoleForward,
+	"SS":  RoleForward,
+	"LW":  RoleForward,
+	"RW":  RoleForward,
+	"FW":  RoleForward,
+}
+
+// PositionRole returns the role group (GK, DEF, MID or FWD) of a position code,
+// or an empty string if the code is unknown.
+func PositionRole(code string) string {
+	return positionRoles[code]
+}
+
+// PositionRoles returns every distinct role covered by the given positions,
+// in the order they first appear.
+func PositionRoles(positions []string) []string {
+	roles := []string{}
+	seen := make(map[string]bool)
+	for _, position := range positions {
+		role := PositionRole(position)
+		if role == "" || seen[role] {
+			continue
+		}
+		seen[role] = true
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// MixesGoalkeeper reports whether the positions include a goalkeeper position
+// alongside outfield ones.
+func MixesGoalkeeper(positions []string) bool {
+	roles := PositionRoles(positions)
+	if len(roles) < 2 {
+		return false
+	}
+	for _, role := range roles {
+		if role == RoleGoalkeeper {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePositions returns all position codes belonging to the given role.
+func RolePositions(role string) []string {
+	positions := []string{}
+	for position, r := range positionRoles {
+		if r == role {
+			positions = append(positions, position)
+		}
+	}
+	sort.Strings(positions)
+	return positions
+}
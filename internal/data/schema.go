@@ -0,0 +1,79 @@
+package data
+
+import "time"
+
+// FootballerSchema returns a JSON Schema for the footballer create and update
+// bodies. It is built from the same limits as ValidateFootballer, so keep the
+// two in step when a rule changes. Rules that depend on other fields, such as
+// year not being before started_play_year, are listed in the description
+// only.
+func FootballerSchema() map[string]interface{} {
+	currentYear := time.Now().Year()
+
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "Footballer",
+		"description": "Create body; PATCH updates accept any subset of the properties, with null meaning unchanged. year must not be before started_play_year. Goalkeepers must not also list outfield positions unless ?allow_mixed=true, and titles must not be more than -max-titles-per-club per played club unless ?allow_outliers=true.",
+		"type":        "object",
+		"required":    []string{"name", "started_play_year", "year", "played_clubs", "position"},
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":      "string",
+				"minLength": 1,
+				"maxLength": 500,
+			},
+			"titles": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 0,
+				"maximum": MaxTitles,
+			},
+			"started_play_year": map[string]interface{}{
+				"type":    "integer",
+				"not":     map[string]interface{}{"const": 0},
+				"maximum": currentYear,
+			},
+			"year": map[string]interface{}{
+				"type":    "integer",
+				"not":     map[string]interface{}{"const": 0},
+				"maximum": currentYear,
+			},
+			"club": map[string]interface{}{
+				"type":      "string",
+				"maxLength": 500,
+			},
+			"played_clubs": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1,
+				"maximum": MaxPlayedClubs,
+			},
+			"position": map[string]interface{}{
+				"type":        "array",
+				"description": "Position codes such as GK, CB or ST; they are uppercased before validation.",
+				"items":       map[string]interface{}{"type": "string"},
+				"minItems":    MinPositions,
+				"maxItems":    MaxPositions,
+				"uniqueItems": true,
+			},
+			"goals": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 0,
+				"maximum": MaxGoals,
+			},
+			"preferred_foot": map[string]interface{}{
+				"type": "string",
+				"enum": append([]string{""}, PreferredFeet...),
+			},
+			"height_cm": map[string]interface{}{
+				"type":    []string{"integer", "null"},
+				"minimum": MinHeightCm,
+				"maximum": MaxHeightCm,
+			},
+			"weight_kg": map[string]interface{}{
+				"type":    []string{"integer", "null"},
+				"minimum": MinWeightKg,
+				"maximum": MaxWeightKg,
+			},
+		},
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,100 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"piscine/internal/validator"
+	"time"
+)
+
+var ErrDuplicateSeason = errors.New("duplicate season")
+
+// SeasonGoals is the number of goals a footballer scored in one season.
+type SeasonGoals struct {
+	ID           int64  `json:"id"`
+	FootballerID int64  `json:"footballer_id"`
+	SeasonYear   int32  `json:"season_year"`
+	Goals        int    `json:"goals"`
+	Club         string `json:"club"`
+}
+
+func ValidateSeasonGoals(v *validator.Validator, season *SeasonGoals) {
+	v.CheckCode(season.SeasonYear != 0, "season_year", "required", "must be provided")
+	v.CheckCode(season.SeasonYear >= 1850, "season_year", "too_small", "must be greater than 1850")
+	v.CheckCode(season.SeasonYear <= int32(time.Now().Year()), "season_year", "in_future", "must not be in the future")
+
+	v.CheckCode(season.Goals >= 0, "goals", "too_small", "must not be negative")
+	v.CheckCode(season.Goals <= MaxGoals, "goals", "too_large", fmt.Sprintf("must not be more than %d", MaxGoals))
+
+	v.CheckCode(len(season.Club) <= 500, "club", "too_long", "must not be more than 500 bytes long")
+}
+
+type SeasonGoalsModel struct {
+	DB *sql.DB
+}
+
+func (m SeasonGoalsModel) Insert(season *SeasonGoals) error {
+	query := `
+INSERT INTO season_goals (footballer_id, season_year, goals, club)
+VALUES ($1, $2, $3, $4)
+RETURNING id`
+
+	args := []interface{}{season.FootballerID, season.SeasonYear, season.Goals, season.Club}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("SeasonGoalsModel.Insert")()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&season.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "season_goals_footballer_id_season_year_key"`:
+			return ErrDuplicateSeason
+		case err.Error() == `pq: insert or update on table "season_goals" violates foreign key constraint "season_goals_footballer_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllForFootballer returns the seasons of a footballer in chronological
+// order.
+func (m SeasonGoalsModel) GetAllForFootballer(footballerID int64) ([]*SeasonGoals, error) {
+	query := `
+SELECT id, footballer_id, season_year, goals, club
+FROM season_goals
+WHERE footballer_id = $1
+ORDER BY season_year`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("SeasonGoalsModel.GetAllForFootballer")()
+
+	rows, err := m.DB.QueryContext(ctx, query, footballerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []*SeasonGoals{}
+
+	for rows.Next() {
+		var season SeasonGoals
+
+		err := rows.Scan(&season.ID, &season.FootballerID, &season.SeasonYear, &season.Goals, &season.Club)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, &season)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return seasons, nil
+}
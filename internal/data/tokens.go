@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"piscine/internal/validator"
 	"time"
 )
@@ -15,12 +16,20 @@ const (
 	ScopeAuthentication = "authentication"
 )
 
+var (
+	ErrTokenExpired = errors.New("token has expired")
+	ErrTokenUsed    = errors.New("token has already been used")
+)
+
 type Token struct {
 	Plaintext string    `json:"token"`
 	Hash      []byte    `json:"-"`
 	UserID    int64     `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	// UsedAt is set once a single-use token, such as an activation token,
+	// has been redeemed.
+	UsedAt *time.Time `json:"-"`
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
@@ -46,8 +55,8 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
-	v.Check(tokenPlaintext != "", "token", "must be provided")
-	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+	v.CheckCode(tokenPlaintext != "", "token", "required", "must be provided")
+	v.CheckCode(len(tokenPlaintext) == 26, "token", "invalid", "must be 26 bytes long")
 }
 
 type TokenModel struct {
@@ -70,16 +79,70 @@ VALUES ($1, $2, $3, $4)`
 	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("TokenModel.Insert")()
 	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
+// Peek returns the token with the given scope and hash without using it up.
+// It returns ErrRecordNotFound for an unknown token and ErrTokenExpired or
+// ErrTokenUsed for one that can no longer be redeemed.
+func (m TokenModel) Peek(scope string, hash []byte) (*Token, error) {
+	query := `
+SELECT user_id, expiry, used_at
+FROM tokens
+WHERE scope = $1 AND hash = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("TokenModel.Peek")()
+
+	token := Token{Hash: hash, Scope: scope}
+	var usedAt sql.NullTime
+	err := m.DB.QueryRowContext(ctx, query, scope, hash).Scan(&token.UserID, &token.Expiry, &usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	switch {
+	case usedAt.Valid:
+		return nil, ErrTokenUsed
+	case !token.Expiry.After(time.Now()):
+		return nil, ErrTokenExpired
+	}
+	return &token, nil
+}
+
+// DeleteAllForUser revokes every token of the given scope belonging to the
+// user and returns how many there were.
+func (m TokenModel) DeleteAllForUser(scope string, userID int64) (int64, error) {
 	query := `
 DELETE FROM tokens
 WHERE scope = $1 AND user_id = $2`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	_, err := m.DB.ExecContext(ctx, query, scope, userID)
-	return err
+	defer timeQuery("TokenModel.DeleteAllForUser")()
+	result, err := m.DB.ExecContext(ctx, query, scope, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (m TokenModel) DeleteExpired() (int64, error) {
+	query := `
+DELETE FROM tokens
+WHERE expiry < $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("TokenModel.DeleteExpired")()
+	result, err := m.DB.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"piscine/internal/validator"
 	"time"
@@ -34,12 +35,17 @@ type password struct {
 	hash      []byte
 }
 
+// BcryptCost is the work factor used when hashing new passwords. Each step
+// doubles the hashing time; cost 12 takes roughly 250ms on current hardware.
+// Existing hashes keep the cost they were created with.
+var BcryptCost = 12
+
 type UserModel struct {
 	DB *sql.DB
 }
 
 func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), BcryptCost)
 	if err != nil {
 		return err
 	}
@@ -62,17 +68,17 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 }
 
 func ValidateEmail(v *validator.Validator, email string) {
-	v.Check(email != "", "email", "must be provided")
-	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+	v.CheckCode(email != "", "email", "required", "must be provided")
+	v.CheckCode(validator.Matches(email, validator.EmailRX), "email", "invalid_format", "must be a valid email address")
 }
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
-	v.Check(password != "", "password", "must be provided")
-	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.CheckCode(password != "", "password", "required", "must be provided")
+	v.CheckCode(len(password) >= 8, "password", "too_short", "must be at least 8 bytes long")
+	v.CheckCode(len(password) <= 72, "password", "too_long", "must not be more than 72 bytes long")
 }
 func ValidateUser(v *validator.Validator, user *User) {
-	v.Check(user.Name != "", "name", "must be provided")
-	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.CheckCode(user.Name != "", "name", "required", "must be provided")
+	v.CheckCode(len(user.Name) <= 500, "name", "too_long", "must not be more than 500 bytes long")
 
 	ValidateEmail(v, user.Email)
 
@@ -93,6 +99,7 @@ RETURNING id, created_at, version`
 	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("UserModel.Insert")()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
 	if err != nil {
@@ -114,6 +121,7 @@ WHERE email = $1`
 	var user User
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("UserModel.GetByEmail")()
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.CreatedAt,
@@ -150,6 +158,7 @@ RETURNING version`
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
+	defer timeQuery("UserModel.Update")()
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
 		switch {
@@ -164,25 +173,25 @@ RETURNING version`
 	return nil
 }
 
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
-
-	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
-
-	query := `
-SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+// tokenUserQuery looks up the user owning a token, along with the token's
+// expiry and use, by token hash and scope.
+const tokenUserQuery = `
+SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version, tokens.expiry, tokens.used_at
 FROM users
 INNER JOIN tokens
 ON users.id = tokens.user_id
 WHERE tokens.hash = $1
-AND tokens.scope = $2
-AND tokens.expiry > $3`
+AND tokens.scope = $2`
 
-	args := []interface{}{tokenHash[:], tokenScope, time.Now()}
+// scanTokenUser reads a row of tokenUserQuery. It returns ErrRecordNotFound
+// for an unknown token and ErrTokenExpired or ErrTokenUsed for one that can no
+// longer be redeemed.
+func scanTokenUser(row *sql.Row) (*User, error) {
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	var expiry time.Time
+	var usedAt sql.NullTime
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
@@ -190,6 +199,8 @@ AND tokens.expiry > $3`
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&expiry,
+		&usedAt,
 	)
 	if err != nil {
 		switch {
@@ -200,5 +211,124 @@ AND tokens.expiry > $3`
 		}
 	}
 
+	switch {
+	case usedAt.Valid:
+		return nil, ErrTokenUsed
+	case !expiry.After(time.Now()):
+		return nil, ErrTokenExpired
+	}
+
 	return &user, nil
 }
+
+func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("UserModel.GetForToken")()
+
+	return scanTokenUser(m.DB.QueryRowContext(ctx, tokenUserQuery, tokenHash[:], tokenScope))
+}
+
+// Activate redeems an activation token and activates its user in a single
+// transaction. Every activation token of the user is marked as used, so none
+// of them can be replayed, and redeeming one again fails with ErrTokenUsed.
+func (m UserModel) Activate(tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("UserModel.Activate")()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := scanTokenUser(tx.QueryRowContext(ctx, tokenUserQuery+"\nFOR UPDATE OF tokens", tokenHash[:], ScopeActivation))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE tokens
+SET used_at = NOW()
+WHERE scope = $1 AND user_id = $2 AND used_at IS NULL`, ScopeActivation, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+UPDATE users
+SET activated = true, version = version + 1
+WHERE id = $1
+RETURNING version`, user.ID).Scan(&user.Version)
+	if err != nil {
+		return nil, err
+	}
+	user.Activated = true
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetAll lists users whose email contains the email argument, ignoring case.
+// It is matched with strpos rather than ILIKE so that % and _ in it are taken
+// literally instead of as wildcards.
+func (m UserModel) GetAll(email string, activated *bool, filters Filters) ([]*User, Metadata, error) {
+	query := fmt.Sprintf(`
+SELECT count(*) OVER(), id, created_at, name, email, activated, version
+FROM users
+WHERE (strpos(lower(email), lower($1)) > 0 OR $1 = '')
+AND (activated = $2 OR $2 IS NULL)
+ORDER BY %s %s, id ASC
+LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	defer timeQuery("UserModel.GetAll")()
+
+	args := []interface{}{email, activated, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	users := []*User{}
+
+	for rows.Next() {
+		var user User
+
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Activated,
+			&user.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return users, metadata, nil
+}
@@ -0,0 +1,93 @@
+// Package events fans out footballer changes to the clients connected to the
+// server-sent events stream.
+package events
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Hub broadcasts published events to every subscriber. Each subscriber gets a
+// small buffer; events are dropped for subscribers that fall behind so a slow
+// client can never block a request handler.
+type Hub struct {
+	mu             sync.Mutex
+	subscribers    map[chan Event]struct{}
+	maxSubscribers int
+	closed         bool
+}
+
+func NewHub(maxSubscribers int) *Hub {
+	return &Hub{
+		subscribers:    make(map[chan Event]struct{}),
+		maxSubscribers: maxSubscribers,
+	}
+}
+
+// Subscribe registers a new subscriber. The returned channel is closed when
+// the hub is closed; call unsubscribe once the client goes away.
+func (h *Hub) Subscribe() (<-chan Event, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed || len(h.subscribers) >= h.maxSubscribers {
+		return nil, nil, ErrTooManySubscribers
+	}
+
+	ch := make(chan Event, 16)
+	h.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+// Publish sends the event to every subscriber without blocking.
+func (h *Hub) Publish(eventType string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := Event{Type: eventType, Data: data}
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of connected subscribers.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers)
+}
+
+// Close disconnects every subscriber and rejects new ones. It is called on
+// shutdown so open streams don't hold up the graceful shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,129 @@
+// Package i18n translates the English validation messages produced by the
+// validator into the client's preferred language.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLanguage = "en"
+
+// catalogs maps a language to translations keyed by the English message.
+// English needs no catalog; messages missing from a catalog stay in English.
+var catalogs = map[string]map[string]string{
+	"ru": {
+		"must be provided":                                       "обязательное поле",
+		"must be a valid email address":                          "должен быть действительным адресом электронной почты",
+		"must be at least 8 bytes long":                          "должен быть не короче 8 байт",
+		"must not be more than 72 bytes long":                    "должен быть не длиннее 72 байт",
+		"must not be more than 500 bytes long":                   "должно быть не длиннее 500 байт",
+		"must be 26 bytes long":                                  "должен быть длиной 26 байт",
+		"must not be in the future":                              "не может быть в будущем",
+		"must not be in the past":                                "не может быть в прошлом",
+		"must not be before started_play_year":                   "не может быть раньше started_play_year",
+		"must not be less than zero":                             "не может быть меньше нуля",
+		"must not be less than 1":                                "не может быть меньше 1",
+		"must not be more than 100":                              "не может быть больше 100",
+		"must not be more than 1000":                             "не может быть больше 1000",
+		"must not be more than 2000":                             "не может быть больше 2000",
+		"must not be negative":                                   "не может быть отрицательным",
+		"must not be negative goals":                             "количество голов не может быть отрицательным",
+		"must not contain duplicate values":                      "не должно содержать повторяющихся значений",
+		"must contain at least 1 position(s)":                    "должно содержать хотя бы 1 позицию",
+		"must be greater than zero":                              "должно быть больше нуля",
+		"must be a maximum of 10 million":                        "должно быть не больше 10 миллионов",
+		"must be an integer value":                               "должно быть целым числом",
+		"must be an RFC3339 timestamp":                           "должно быть меткой времени в формате RFC3339",
+		"must not mix GK with outfield positions":                "не может сочетать GK с позициями полевого игрока",
+		"must be rfc3339 or unix":                                "должно быть rfc3339 или unix",
+		"invalid position value":                                 "недопустимое значение позиции",
+		"must contain only positive ids":                         "должно содержать только положительные идентификаторы",
+		"must be a boolean value":                                "должно быть логическим значением",
+		"must be a positive integer":                             "должно быть положительным целым числом",
+		"must be different from keep":                            "должно отличаться от keep",
+		"must be a comma-separated list of positive integer ids": "должно быть списком положительных целых id через запятую",
+		"must contain exactly 2 ids":                             "должно содержать ровно 2 id",
+		"must be insert or upsert":                               "должно быть insert или upsert",
+		"must be error or ignore":                                "должно быть error или ignore",
+		"must be a single character, not a quote or newline":     "должно быть одним символом, кроме кавычки и перевода строки",
+		"must be utf-8 or latin1":                                "должно быть utf-8 или latin1",
+		"a footballer with this name and club already exists":    "футболист с таким именем и клубом уже существует",
+		"must be all or any":                                     "должно быть all или any",
+		"must be left, right or both":                            "должно быть left, right или both",
+		"must be between 140 and 220":                            "должно быть от 140 до 220",
+		"must be between 40 and 150":                             "должно быть от 40 до 150",
+		"must be metric or imperial":                             "должно быть metric или imperial",
+		"must not be less than min_height":                       "не может быть меньше min_height",
+		"must not be more than 20 per played club":               "не может быть больше 20 на каждый клуб",
+		"must be greater than 1850":                              "должно быть больше 1850",
+		"a season already exists for this year":                  "сезон за этот год уже существует",
+		"must not be set unless injured":                         "можно указать только для травмированного игрока",
+		"invalid sort value":                                     "недопустимое значение сортировки",
+		"must be a column name, optionally prefixed by one -":    "должно быть именем столбца, возможно с одним - в начале",
+		"invalid role value":                                     "недопустимое значение роли",
+		"invalid activation token":                               "недействительный токен активации",
+		"activation token has expired":                           "срок действия токена активации истёк",
+		"activation token has already been used":                 "токен активации уже использован",
+		"a user with this email address already exists":          "пользователь с таким адресом электронной почты уже существует",
+	},
+}
+
+// Supported reports whether messages can be translated into lang.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == DefaultLanguage
+}
+
+// Translate returns message in the given language, or the original English
+// message if no translation exists.
+func Translate(lang, message string) string {
+	if translated, ok := catalogs[lang][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// PreferredLanguage picks the supported language with the highest quality
+// value from an Accept-Language header, falling back to English.
+func PreferredLanguage(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.Index(lang, "-"); i >= 0 {
+			lang = lang[:i]
+		}
+		if lang == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = value
+				}
+			}
+		}
+		candidates = append(candidates, candidate{lang: lang, q: q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, c := range candidates {
+		if c.q > 0 && Supported(c.lang) {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
@@ -11,6 +11,7 @@ type Level int8
 
 const (
 	LevelInfo Level = iota
+	LevelWarn
 	LevelError
 	LevelFatal
 	LevelOff
@@ -20,6 +21,8 @@ func (l Level) String() string {
 	switch l {
 	case LevelInfo:
 		return "INFO"
+	case LevelWarn:
+		return "WARN"
 	case LevelError:
 		return "ERROR"
 	case LevelFatal:
@@ -45,6 +48,9 @@ func New(out io.Writer, minLevel Level) *Logger {
 func (l *Logger) PrintInfo(message string, properties map[string]string) {
 	l.print(LevelInfo, message, properties)
 }
+func (l *Logger) PrintWarn(message string, properties map[string]string) {
+	l.print(LevelWarn, message, properties)
+}
 func (l *Logger) PrintError(err error, properties map[string]string) {
 	l.print(LevelError, err.Error(), properties)
 }
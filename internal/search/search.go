@@ -0,0 +1,154 @@
+// Package search mirrors footballer writes into an external full-text search
+// engine. PostgreSQL stays the source of truth; the index only serves searches.
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"piscine/internal/data"
+	"strconv"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("search engine not configured")
+
+type Indexer interface {
+	Index(footballer *data.Footballer) error
+	Delete(id int64) error
+	Search(query string, from, size int) ([]int64, int, error)
+}
+
+// NoopIndexer is used when no search engine is configured. Writes are ignored
+// and searches fail with ErrNotConfigured.
+type NoopIndexer struct{}
+
+func (NoopIndexer) Index(footballer *data.Footballer) error { return nil }
+
+func (NoopIndexer) Delete(id int64) error { return nil }
+
+func (NoopIndexer) Search(query string, from, size int) ([]int64, int, error) {
+	return nil, 0, ErrNotConfigured
+}
+
+// Elasticsearch talks to an Elasticsearch or OpenSearch cluster over its REST API.
+type Elasticsearch struct {
+	url    string
+	index  string
+	client *http.Client
+}
+
+func NewElasticsearch(baseURL, index string) *Elasticsearch {
+	return &Elasticsearch{
+		url:    baseURL,
+		index:  index,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (es *Elasticsearch) Index(footballer *data.Footballer) error {
+	// Pin the time format so the index mapping doesn't depend on
+	// -time-format.
+	doc := *footballer
+	doc.TimeFormat = data.TimeFormatRFC3339
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return es.do(http.MethodPut, es.docURL(footballer.ID), body, nil)
+}
+
+func (es *Elasticsearch) Delete(id int64) error {
+	err := es.do(http.MethodDelete, es.docURL(id), nil, nil)
+	if errors.Is(err, errNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Search runs a match query against the name and club fields and returns the
+// matching footballer ids in relevance order, along with the total hit count.
+func (es *Elasticsearch) Search(query string, from, size int) ([]int64, int, error) {
+	request := map[string]interface{}{
+		"from":    from,
+		"size":    size,
+		"_source": false,
+		"query":   map[string]interface{}{"match_all": map[string]interface{}{}},
+	}
+	if query != "" {
+		request["query"] = map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  query,
+				"fields": []string{"name", "club"},
+			},
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	err = es.do(http.MethodPost, fmt.Sprintf("%s/%s/_search", es.url, url.PathEscape(es.index)), body, &response)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]int64, 0, len(response.Hits.Hits))
+	for _, hit := range response.Hits.Hits {
+		id, err := strconv.ParseInt(hit.ID, 10, 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, response.Hits.Total.Value, nil
+}
+
+var errNotFound = errors.New("search: document not found")
+
+func (es *Elasticsearch) docURL(id int64) string {
+	return fmt.Sprintf("%s/%s/_doc/%d", es.url, url.PathEscape(es.index), id)
+}
+
+func (es *Elasticsearch) do(method, url string, body []byte, dst interface{}) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := es.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return fmt.Errorf("search: %s %s returned status %d", method, url, res.StatusCode)
+	}
+
+	if dst != nil {
+		return json.NewDecoder(res.Body).Decode(dst)
+	}
+	return nil
+}
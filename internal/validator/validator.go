@@ -1,26 +1,41 @@
 package validator
 
-import "regexp"
+import (
+	"regexp"
+)
 
 var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// Validator collects one error message per key. Alongside each message it
+// keeps a stable machine-readable code such as "name.required", so clients
+// don't have to match on the English wording.
 type Validator struct {
 	Errors map[string]string
+	Codes  map[string]string
 }
 
 func New() *Validator {
-	return &Validator{Errors: make(map[string]string)}
+	return &Validator{Errors: make(map[string]string), Codes: make(map[string]string)}
 }
 
 func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
 
+// AddError records message under key with the generic "invalid" code. Prefer
+// AddErrorCode when the failure has a more specific code.
 func (v *Validator) AddError(key, message string) {
+	v.AddErrorCode(key, "invalid", message)
+}
+
+// AddErrorCode records message under key with an explicit code, which is
+// prefixed with the key.
+func (v *Validator) AddErrorCode(key, code, message string) {
 	if _, exists := v.Errors[key]; !exists {
 		v.Errors[key] = message
+		v.Codes[key] = key + "." + code
 	}
 }
 
@@ -30,6 +45,13 @@ func (v *Validator) Check(ok bool, key, message string) {
 	}
 }
 
+// CheckCode is Check with an explicit code.
+func (v *Validator) CheckCode(ok bool, key, code, message string) {
+	if !ok {
+		v.AddErrorCode(key, code, message)
+	}
+}
+
 func In(value string, list ...string) bool {
 	for i := range list {
 		if value == list[i] {
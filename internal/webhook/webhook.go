@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"piscine/internal/jsonlog"
+	"time"
+)
+
+const maxAttempts = 3
+
+type Event struct {
+	Type string      `json:"event"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to a single webhook URL from a buffered queue, so
+// request handlers never wait on the remote service.
+type Dispatcher struct {
+	url    string
+	secret string
+	client *http.Client
+	queue  chan Event
+	logger *jsonlog.Logger
+}
+
+func New(url, secret string, queueSize int, logger *jsonlog.Logger) *Dispatcher {
+	return &Dispatcher{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 5 * time.Second},
+		queue:  make(chan Event, queueSize),
+		logger: logger,
+	}
+}
+
+// Enqueue adds an event to the queue without blocking. The event is dropped
+// and logged if the queue is full.
+func (d *Dispatcher) Enqueue(eventType string, data interface{}) {
+	event := Event{Type: eventType, Time: time.Now().UTC(), Data: data}
+
+	select {
+	case d.queue <- event:
+	default:
+		d.logger.PrintError(fmt.Errorf("webhook queue full, dropping %s event", eventType), nil)
+	}
+}
+
+// QueueDepth returns the number of events waiting to be delivered and the
+// queue capacity.
+func (d *Dispatcher) QueueDepth() (int, int) {
+	return len(d.queue), cap(d.queue)
+}
+
+// Run delivers queued events until stop is closed, then flushes whatever is
+// still queued before returning.
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case event := <-d.queue:
+			d.deliver(event)
+		case <-stop:
+			for {
+				select {
+				case event := <-d.queue:
+					d.deliver(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.PrintError(err, map[string]string{"event": event.Type})
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(d.secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = d.post(body, signature)
+		if err == nil {
+			return
+		}
+
+		if attempt < maxAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+
+	d.logger.PrintError(err, map[string]string{
+		"event":    event.Type,
+		"url":      d.url,
+		"attempts": fmt.Sprintf("%d", maxAttempts),
+	})
+}
+
+func (d *Dispatcher) post(body []byte, signature string) error {
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Signature", signature)
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook delivery failed with status %d", res.StatusCode)
+	}
+	return nil
+}
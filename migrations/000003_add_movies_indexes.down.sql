@@ -1,2 +1,2 @@
-DROP INDEX IF EXISTS footballers_name_idx
+DROP INDEX IF EXISTS footballers_name_idx;
 DROP INDEX IF EXISTS footballers_positions_idx;
\ No newline at end of file
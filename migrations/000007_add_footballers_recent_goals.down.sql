@@ -0,0 +1,2 @@
+ALTER TABLE footballers DROP CONSTRAINT IF EXISTS footballers_recent_goals_check;
+ALTER TABLE footballers DROP COLUMN IF EXISTS recent_goals;
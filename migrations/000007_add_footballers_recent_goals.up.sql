@@ -0,0 +1,2 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS recent_goals integer NOT NULL DEFAULT 0;
+ALTER TABLE footballers ADD CONSTRAINT footballers_recent_goals_check CHECK (recent_goals >= 0);
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE code = 'footballers:verify';
+
+ALTER TABLE footballers DROP COLUMN IF EXISTS verified;
@@ -0,0 +1,4 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS verified boolean NOT NULL DEFAULT false;
+
+INSERT INTO permissions (code)
+VALUES ('footballers:verify');
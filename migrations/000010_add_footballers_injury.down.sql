@@ -0,0 +1,2 @@
+ALTER TABLE footballers DROP COLUMN IF EXISTS injury_return_date;
+ALTER TABLE footballers DROP COLUMN IF EXISTS injured;
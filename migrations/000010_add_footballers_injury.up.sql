@@ -0,0 +1,2 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS injured boolean NOT NULL DEFAULT false;
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS injury_return_date date;
@@ -0,0 +1,2 @@
+ALTER TABLE footballers DROP CONSTRAINT IF EXISTS footballers_preferred_foot_check;
+ALTER TABLE footballers DROP COLUMN IF EXISTS preferred_foot;
@@ -0,0 +1,2 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS preferred_foot text;
+ALTER TABLE footballers ADD CONSTRAINT footballers_preferred_foot_check CHECK (preferred_foot IN ('left', 'right', 'both'));
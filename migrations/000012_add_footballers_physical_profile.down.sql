@@ -0,0 +1,4 @@
+ALTER TABLE footballers DROP CONSTRAINT IF EXISTS footballers_weight_kg_check;
+ALTER TABLE footballers DROP CONSTRAINT IF EXISTS footballers_height_cm_check;
+ALTER TABLE footballers DROP COLUMN IF EXISTS weight_kg;
+ALTER TABLE footballers DROP COLUMN IF EXISTS height_cm;
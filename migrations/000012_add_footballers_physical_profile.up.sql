@@ -0,0 +1,4 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS height_cm integer;
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS weight_kg integer;
+ALTER TABLE footballers ADD CONSTRAINT footballers_height_cm_check CHECK (height_cm BETWEEN 140 AND 220);
+ALTER TABLE footballers ADD CONSTRAINT footballers_weight_kg_check CHECK (weight_kg BETWEEN 40 AND 150);
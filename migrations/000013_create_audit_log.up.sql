@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    action text NOT NULL,
+    footballer_id bigint NOT NULL,
+    user_id bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    changes jsonb NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_footballer_id_idx ON audit_log (footballer_id);
+
+INSERT INTO permissions (code)
+VALUES ('audit:read');
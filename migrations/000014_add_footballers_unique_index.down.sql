@@ -0,0 +1 @@
+DROP INDEX IF EXISTS footballers_names_club_startedplayyear_key;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS footballers_names_club_startedplayyear_key ON footballers (names, club, startedplayyear);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS season_goals (
+    id bigserial PRIMARY KEY,
+    footballer_id bigint NOT NULL REFERENCES footballers ON DELETE CASCADE,
+    season_year integer NOT NULL,
+    goals integer NOT NULL DEFAULT 0,
+    club text NOT NULL DEFAULT '',
+    CONSTRAINT season_goals_footballer_id_season_year_key UNIQUE (footballer_id, season_year),
+    CONSTRAINT season_goals_goals_check CHECK (goals >= 0)
+);
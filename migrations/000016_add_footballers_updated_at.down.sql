@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS footballers_updated_at_idx;
+ALTER TABLE footballers DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,3 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+UPDATE footballers SET updated_at = created_at;
+CREATE INDEX IF NOT EXISTS footballers_updated_at_idx ON footballers (updated_at);
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS footballers_name_idx ON footballers USING GIN (to_tsvector('simple', names));
+DROP INDEX IF EXISTS footballers_search_vector_idx;
+DROP TRIGGER IF EXISTS footballers_search_vector_trigger ON footballers;
+DROP FUNCTION IF EXISTS footballers_search_vector_update();
+ALTER TABLE footballers DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,17 @@
+ALTER TABLE footballers ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+UPDATE footballers SET search_vector = to_tsvector('simple', names);
+
+CREATE OR REPLACE FUNCTION footballers_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := to_tsvector('simple', NEW.names);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER footballers_search_vector_trigger
+BEFORE INSERT OR UPDATE OF names ON footballers
+FOR EACH ROW EXECUTE FUNCTION footballers_search_vector_update();
+
+CREATE INDEX IF NOT EXISTS footballers_search_vector_idx ON footballers USING GIN (search_vector);
+DROP INDEX IF EXISTS footballers_name_idx;
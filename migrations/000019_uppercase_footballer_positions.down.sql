@@ -0,0 +1 @@
+-- The original casing of positions is not kept, so there is nothing to undo.
@@ -0,0 +1,16 @@
+-- Trim and uppercase positions, dropping the duplicates that folding case can
+-- create (e.g. {st,ST}) while keeping each position's first place.
+UPDATE footballers
+SET positions = normalized.positions
+FROM (
+    SELECT f.id, ARRAY(
+        SELECT position
+        FROM unnest(f.positions) WITH ORDINALITY AS u(raw, n)
+        CROSS JOIN LATERAL (SELECT upper(trim(raw)) AS position) AS p
+        GROUP BY position
+        ORDER BY min(n)
+    ) AS positions
+    FROM footballers f
+) AS normalized
+WHERE footballers.id = normalized.id
+AND footballers.positions IS DISTINCT FROM normalized.positions;
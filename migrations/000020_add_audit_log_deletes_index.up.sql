@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS audit_log_deletes_idx ON audit_log (created_at) WHERE action = 'delete';
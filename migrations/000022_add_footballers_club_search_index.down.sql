@@ -0,0 +1 @@
+DROP INDEX IF EXISTS footballers_club_search_idx;
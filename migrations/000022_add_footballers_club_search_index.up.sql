@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS footballers_club_search_idx ON footballers USING GIN (to_tsvector('simple', club));
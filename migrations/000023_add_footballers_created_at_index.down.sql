@@ -0,0 +1 @@
+DROP INDEX IF EXISTS footballers_created_at_idx;
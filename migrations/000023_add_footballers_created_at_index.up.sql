@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS footballers_created_at_idx ON footballers (created_at DESC, id DESC);
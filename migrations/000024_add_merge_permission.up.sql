@@ -0,0 +1,2 @@
+INSERT INTO permissions (code)
+VALUES ('footballers:merge');